@@ -2,28 +2,41 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/aneshas/flags"
 	"github.com/aneshas/flags/env"
 	"github.com/aneshas/gocask"
 	"github.com/aneshas/gocask/core"
 	"github.com/aneshas/gocask/rpc"
+	"github.com/twitchtv/twirp"
 	"log"
 	"net/http"
 	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
 )
 
 func main() {
 	var fs flags.FlagSet
 
+	cmd, args := command(os.Args)
+
 	var (
 		dataDir = fs.String("datadir", "Directory where databases are stored (default ~/gcdata)", "", env.Named("DATADIR"))
 		dbName  = fs.String("db", "DB name to connect to", "default", env.Named("DBNAME"))
 		maxSize = fs.Int64("maxsize", "Max data file size in bytes (default 2GB)", 0, env.Named("MAX_DATA_FILE_SIZE"))
 		port    = fs.Int("port", "Server port", 8888, env.Named("PORT"))
+		syncP   = fs.String("sync", "Data file sync policy: never, always or background sync interval eg. 100ms (default never)", "", env.Named("SYNC"))
+		scrub   = fs.String("scrub", "Background scrub interval of immutable data files eg. 24h (default disabled)", "", env.Named("SCRUB"))
+		rate    = fs.Int64("scrubrate", "Max scrub read rate in bytes per second (default unlimited)", 0, env.Named("SCRUB_RATE"))
+		strict  = fs.Bool("strict", "Refuse to start if the active data file ends with a torn entry instead of truncating it", false, env.Named("STRICT"))
+		cache   = fs.Int64("cache", "Max total size of values cached in memory in bytes (default disabled)", 0, env.Named("VALUE_CACHE_SIZE"))
+		mmap    = fs.Bool("mmap", "Read immutable data files through memory mappings", false, env.Named("MMAP"))
 	)
 
-	fs.Parse(os.Args)
+	fs.Parse(args)
 
 	var opts []gocask.Option
 
@@ -35,6 +48,63 @@ func main() {
 		opts = append(opts, gocask.WithDataDir(*dataDir))
 	}
 
+	if *syncP != "" {
+		policy, err := parseSyncPolicy(*syncP)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		opts = append(opts, gocask.WithSyncPolicy(policy))
+	}
+
+	if *scrub != "" {
+		interval, err := time.ParseDuration(*scrub)
+		if err != nil {
+			log.Fatalf("invalid scrub interval %q: %v", *scrub, err)
+		}
+
+		opts = append(opts, gocask.WithScrubPolicy(core.ScrubPolicy{
+			Interval:       interval,
+			BytesPerSecond: *rate,
+			OnCorruption: func(fr core.FileReport) {
+				log.Printf(
+					"gocask: data file %s is damaged (%d crc failures, %d torn records)",
+					fr.File, fr.CRCFailures, fr.TornRecords,
+				)
+			},
+		}))
+	}
+
+	if *strict {
+		opts = append(opts, gocask.WithStrictMode())
+	}
+
+	if *cache > 0 {
+		opts = append(opts, gocask.WithValueCache(*cache))
+	}
+
+	if *mmap {
+		opts = append(opts, gocask.WithMMap())
+	}
+
+	switch cmd {
+	case "":
+	case "migrate":
+		migrate(*dbName, opts)
+
+		return
+	case "verify":
+		verify(*dbName, opts)
+
+		return
+	case "repair":
+		repair(*dbName, opts)
+
+		return
+	default:
+		log.Fatalf("unknown command %q (supported commands: migrate, verify, repair)", cmd)
+	}
+
 	fmt.Printf("Opening %s database...", *dbName)
 
 	db, err := gocask.Open(*dbName, opts...)
@@ -51,25 +121,124 @@ func main() {
 	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", *port), twirpServer))
 }
 
+// command splits the subcommand (if any) from the flags.
+// Without a subcommand gocask runs the db server
+func command(args []string) (string, []string) {
+	if len(args) < 2 || strings.HasPrefix(args[1], "-") {
+		return "", args
+	}
+
+	return args[1], append([]string{args[0]}, args[2:]...)
+}
+
+// migrate rewrites the database in the current data file format.
+// Opening the database moves new writes to a data file of the current format
+// and merge rewrites all the other data files
+func migrate(dbName string, opts []gocask.Option) {
+	fmt.Printf("Migrating %s database...", dbName)
+
+	db, err := gocask.Open(dbName, opts...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = db.Merge()
+	if err != nil {
+		_ = db.Close()
+
+		log.Fatal(err)
+	}
+
+	err = db.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(" Done.")
+}
+
+// verify reports the integrity of all data files and exits with non-zero status if any of them is damaged
+func verify(dbName string, opts []gocask.Option) {
+	report, err := gocask.Verify(dbName, opts...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	printReport(report)
+
+	if report.Damaged() {
+		os.Exit(1)
+	}
+}
+
+// repair salvages all valid entries of the database into a fresh set of data files
+func repair(dbName string, opts []gocask.Option) {
+	fmt.Printf("Repairing %s database...\n", dbName)
+
+	report, err := gocask.Repair(dbName, opts...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	printReport(report)
+
+	fmt.Println("Done.")
+}
+
+func printReport(report *core.Report) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "FILE\tENTRIES\tCRC FAILURES\tTORN RECORDS\tORPHANED TOMBSTONES\tDAMAGED BYTES")
+
+	for _, fr := range report.Files {
+		fmt.Fprintf(
+			w,
+			"%s\t%d\t%d\t%d\t%d\t%d\n",
+			fr.File, fr.Entries, fr.CRCFailures, fr.TornRecords, fr.OrphanedTombstones, fr.DamagedBytes,
+		)
+	}
+
+	_ = w.Flush()
+}
+
+func parseSyncPolicy(policy string) (core.SyncPolicy, error) {
+	switch policy {
+	case "never":
+		return core.SyncNever, nil
+	case "always":
+		return core.SyncAlways, nil
+	}
+
+	interval, err := time.ParseDuration(policy)
+	if err != nil {
+		return core.SyncPolicy{}, fmt.Errorf("invalid sync policy %q: %w", policy, err)
+	}
+
+	return core.SyncEvery(interval), nil
+}
+
 type server struct {
 	db *core.DB
 }
 
 // Put a value
 func (g *server) Put(_ context.Context, request *rpc.PutRequest) (*rpc.Empty, error) {
-	return &rpc.Empty{}, g.db.Put(request.Key, request.Value)
+	_, err := g.db.Put(request.Key, request.Value)
+
+	return &rpc.Empty{}, err
 }
 
 // Get a value
 func (g *server) Get(_ context.Context, request *rpc.GetRequest) (*rpc.Entry, error) {
-	val, err := g.db.Get(request.Key)
+	val, version, err := g.db.GetWithVersion(request.Key)
 	if err != nil {
 		return nil, err
 	}
 
 	return &rpc.Entry{
-		Key:   request.Key,
-		Value: val,
+		Key:     request.Key,
+		Value:   val,
+		Version: version,
 	}, nil
 }
 
@@ -86,3 +255,46 @@ func (g *server) Keys(_ context.Context, _ *rpc.Empty) (*rpc.KeysResponse, error
 		Mkeys: keys,
 	}, nil
 }
+
+// CompareAndSwap replaces a value if it equals the expected one and returns the version of the new value
+func (g *server) CompareAndSwap(_ context.Context, request *rpc.CompareAndSwapRequest) (*rpc.PutResponse, error) {
+	version, err := g.db.CompareAndSwap(request.Key, request.Expected, request.Value)
+	if err != nil {
+		return nil, conditionError(err)
+	}
+
+	return &rpc.PutResponse{Version: version}, nil
+}
+
+// PutIfAbsent puts a value if the key does not exist and returns its version
+func (g *server) PutIfAbsent(_ context.Context, request *rpc.PutRequest) (*rpc.PutResponse, error) {
+	version, err := g.db.PutIfAbsent(request.Key, request.Value)
+	if err != nil {
+		return nil, conditionError(err)
+	}
+
+	return &rpc.PutResponse{Version: version}, nil
+}
+
+// PutIfVersion puts a value if the stored one has the given version and returns the version of the new value
+func (g *server) PutIfVersion(_ context.Context, request *rpc.PutIfVersionRequest) (*rpc.PutResponse, error) {
+	version, err := g.db.PutIfVersion(request.Key, request.Value, request.Version)
+	if err != nil {
+		return nil, conditionError(err)
+	}
+
+	return &rpc.PutResponse{Version: version}, nil
+}
+
+// conditionError reports failed conditions of a conditional put with twirp error codes,
+// so that clients can tell them from server faults
+func conditionError(err error) error {
+	switch {
+	case errors.Is(err, core.ErrConditionFailed):
+		return twirp.NewError(twirp.FailedPrecondition, err.Error())
+	case errors.Is(err, core.ErrKeyNotFound):
+		return twirp.NotFoundError(err.Error())
+	}
+
+	return err
+}
@@ -0,0 +1,189 @@
+package core
+
+import (
+	"errors"
+	"time"
+)
+
+// errTornBatch signifies that a batch was not written completely
+// in which case none of its operations are applied
+var errTornBatch = errors.New("gocask: batch not fully written")
+
+// Batch represents a group of Put and Delete operations which are written atomically with DB.Write.
+// Key and value slices are retained by the batch so they should not be modified until the batch is written
+type Batch struct {
+	ops []batchOp
+}
+
+type batchOp struct {
+	key, val []byte
+	ttl      time.Duration
+	expiring bool
+	delete   bool
+}
+
+// Put adds a put operation to the batch
+func (b *Batch) Put(key, val []byte) {
+	b.ops = append(b.ops, batchOp{
+		key: key,
+		val: val,
+	})
+}
+
+// PutWithTTL adds a put operation of an expiring value to the batch
+// TTL is rounded up to a whole second and should be positive
+func (b *Batch) PutWithTTL(key, val []byte, ttl time.Duration) {
+	b.ops = append(b.ops, batchOp{
+		key:      key,
+		val:      val,
+		ttl:      ttl,
+		expiring: true,
+	})
+}
+
+// Delete adds a delete operation to the batch
+// Unlike DB.Delete, deleting a non-existent key is not reported as an error
+func (b *Batch) Delete(key []byte) {
+	b.ops = append(b.ops, batchOp{
+		key:    key,
+		delete: true,
+	})
+}
+
+// Len returns the number of operations in the batch
+func (b *Batch) Len() int {
+	return len(b.ops)
+}
+
+// Reset removes all operations from the batch so that it can be reused
+func (b *Batch) Reset() {
+	b.ops = b.ops[:0]
+}
+
+func (b *Batch) validate() error {
+	for _, op := range b.ops {
+		if len(op.key) == 0 {
+			return ErrInvalidKey
+		}
+
+		if op.delete {
+			err := checkEntrySize(op.key, nil)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if op.val == nil {
+			return ErrInvalidValue
+		}
+
+		err := checkEntrySize(op.key, op.val)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := ttlSeconds(op.ttl); op.expiring && !ok {
+			return ErrInvalidTTL
+		}
+	}
+
+	return nil
+}
+
+// Write atomically applies all operations of the batch. Operations are applied in the order they were added
+// and the batch is stored as a single record, so upon startup either all or none of its operations are applied.
+// Concurrent reads are not blocked by the write, so they may observe some of its operations before the others
+// (snapshots never do)
+func (db *DB) Write(b *Batch) error {
+	if db.cfg.ReadOnly {
+		return ErrReadOnly
+	}
+
+	if b.Len() == 0 {
+		return nil
+	}
+
+	err := b.validate()
+	if err != nil {
+		return err
+	}
+
+	db.m.RLock()
+	defer db.m.RUnlock()
+
+	db.wm.Lock()
+	defer db.wm.Unlock()
+
+	now := db.time.NowUnix()
+	headers := make([]header, len(b.ops))
+
+	var entries []byte
+
+	for i, op := range b.ops {
+		seq := db.seq + uint64(i) + 1
+
+		if op.delete {
+			headers[i] = newKVHeader(now, 0, seq, nil, op.key)
+			entries = append(entries, serializeEntry(headers[i], nil, op.key)...)
+
+			continue
+		}
+
+		var expiry uint32
+
+		if op.expiring {
+			ttl, _ := ttlSeconds(op.ttl)
+
+			expiry, err = expiryAt(now, ttl)
+			if err != nil {
+				return err
+			}
+		}
+
+		headers[i] = newKVHeader(now, expiry, seq, op.key, op.val)
+
+		entries = append(entries, serializeEntry(headers[i], op.key, op.val)...)
+	}
+
+	if uint64(len(entries)) > maxValueSize {
+		return ErrEntryTooLarge
+	}
+
+	h := newBatchHeader(now, entries)
+
+	err = db.rotateDataFile(int64(h.entrySize()))
+	if err != nil {
+		return err
+	}
+
+	// Sequence numbers of a batch which failed to be written are not assigned again
+	db.seq += uint64(len(b.ops))
+
+	err = db.writeKeyVal(h, nil, entries)
+	if err != nil {
+		return err
+	}
+
+	db.kd.advanceOffsetBy(uint64(h.size()))
+
+	for i, op := range b.ops {
+		db.cache.invalidate(op.key)
+
+		if op.delete {
+			db.kd.unset(op.key, headers[i])
+
+			continue
+		}
+
+		db.kd.set(op.key, headers[i], db.file.Name())
+	}
+
+	// Watchers are notified once the operations can be read (see applyStaged)
+	for i, op := range b.ops {
+		db.watchers.notify(op.key, headers[i])
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package core_test
+
+import (
+	"github.com/aneshas/gocask/core"
+	"github.com/aneshas/gocask/core/testutil"
+	caskfs "github.com/aneshas/gocask/internal/fs"
+	"github.com/stretchr/testify/assert"
+	"os"
+	gopath "path"
+	"testing"
+)
+
+func TestBatch_Should_Apply_All_Operations(t *testing.T) {
+	fs := caskfs.NewInMemory()
+
+	var time testutil.Time
+
+	db, _ := core.NewDB("", fs, time, core.DefaultConfig)
+
+	_, _ = db.Put([]byte("foo"), []byte("old"))
+	_, _ = db.Put([]byte("bar"), []byte("bar"))
+
+	var b core.Batch
+
+	b.Put([]byte("foo"), []byte("new"))
+	b.Put([]byte("baz"), []byte("baz"))
+	b.Delete([]byte("bar"))
+
+	err := db.Write(&b)
+
+	assert.NoError(t, err)
+
+	assertBatchApplied := func(db *core.DB) {
+		got, err := db.Get([]byte("foo"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("new"), got)
+
+		got, err = db.Get([]byte("baz"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("baz"), got)
+
+		_, err = db.Get([]byte("bar"))
+
+		assert.ErrorIs(t, err, core.ErrKeyNotFound)
+	}
+
+	assertBatchApplied(db)
+
+	_, _ = db.Put([]byte("afterbatch"), []byte("value"))
+
+	db, err = core.NewDB("", fs, time, core.DefaultConfig)
+
+	assert.NoError(t, err)
+
+	assertBatchApplied(db)
+
+	got, err := db.Get([]byte("afterbatch"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), got)
+}
+
+func TestBatch_Should_Not_Write_Anything_For_Invalid_Operation(t *testing.T) {
+	db := getInMemDB(t)
+
+	var b core.Batch
+
+	b.Put([]byte("foo"), []byte("bar"))
+	b.Put(nil, []byte("bar"))
+
+	err := db.Write(&b)
+
+	assert.ErrorIs(t, err, core.ErrInvalidKey)
+
+	b.Reset()
+
+	b.Put([]byte("foo"), []byte("bar"))
+	b.PutWithTTL([]byte("baz"), []byte("bar"), 0)
+
+	err = db.Write(&b)
+
+	assert.ErrorIs(t, err, core.ErrInvalidTTL)
+
+	assert.Equal(t, []string{}, db.Keys())
+}
+
+func TestBatch_Should_Not_Apply_Torn_Batch_On_Startup(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_batch")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	var time testutil.Time
+
+	disk := caskfs.NewDisk()
+	cfg := core.Config{MaxDataFileSize: core.DefaultConfig.MaxDataFileSize}
+
+	db, _ := core.NewDB(dbPath, disk, time, cfg)
+
+	_, _ = db.Put([]byte("foo"), []byte("bar"))
+
+	var b core.Batch
+
+	b.Put([]byte("foo"), []byte("overwritten"))
+	b.Put([]byte("baz"), []byte("baz"))
+
+	assert.NoError(t, db.Write(&b))
+	assert.NoError(t, db.Close())
+
+	files, _ := disk.Files(dbPath)
+	dataFile := gopath.Join(dbPath, files[0]+".csk")
+
+	info, _ := os.Stat(dataFile)
+
+	assert.NoError(t, os.Truncate(dataFile, info.Size()-2))
+
+	db, err = core.NewDB(dbPath, disk, time, cfg)
+
+	assert.NoError(t, err)
+
+	got, err := db.Get([]byte("foo"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("bar"), got)
+
+	_, err = db.Get([]byte("baz"))
+
+	assert.ErrorIs(t, err, core.ErrKeyNotFound)
+
+	_, err = db.Put([]byte("baz"), []byte("after restart"))
+
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+
+	db, err = core.NewDB(dbPath, disk, time, cfg)
+
+	assert.NoError(t, err)
+
+	got, err = db.Get([]byte("baz"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("after restart"), got)
+}
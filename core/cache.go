@@ -0,0 +1,153 @@
+package core
+
+import (
+	"container/list"
+	"sync"
+)
+
+// CacheStats holds the statistics of the value cache
+type CacheStats struct {
+	// Hits is the number of Get calls served from the cache
+	Hits uint64
+
+	// Misses is the number of Get calls which had to read the value from a data file
+	Misses uint64
+
+	// Entries is the number of cached values
+	Entries int
+
+	// Size is the total size of cached values in bytes
+	Size int64
+}
+
+// HitRatio returns the ratio of Get calls served from the cache (0 if nothing was read yet)
+func (s CacheStats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// CacheStats returns the statistics of the value cache
+func (db *DB) CacheStats() CacheStats {
+	return db.cache.stats()
+}
+
+// valueCache keeps recently read and written values in memory and evicts the least recently used ones
+// once their total size exceeds the max size. Cached value is served only if it was cached for the current
+// keydir entry of its key, so that values moved by merge or rewritten by another process are read again
+type valueCache struct {
+	m      sync.Mutex
+	max    int64
+	size   int64
+	lru    *list.List
+	values map[string]*list.Element
+
+	hits   uint64
+	misses uint64
+}
+
+type cachedValue struct {
+	key   string
+	entry kdEntry
+	val   []byte
+}
+
+func newValueCache(max int64) *valueCache {
+	return &valueCache{
+		max:    max,
+		lru:    list.New(),
+		values: map[string]*list.Element{},
+	}
+}
+
+// get returns a copy of the value cached for the keydir entry of the key
+func (c *valueCache) get(key []byte, entry kdEntry) ([]byte, bool) {
+	if c.max == 0 {
+		return nil, false
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	e, ok := c.values[string(key)]
+	if !ok || e.Value.(*cachedValue).entry != entry {
+		c.misses++
+
+		return nil, false
+	}
+
+	c.hits++
+
+	c.lru.MoveToFront(e)
+
+	return append([]byte{}, e.Value.(*cachedValue).val...), true
+}
+
+// set caches a copy of the value of the keydir entry of the key. Values larger than the cache are not cached
+func (c *valueCache) set(key []byte, entry kdEntry, val []byte) {
+	if c.max == 0 {
+		return
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.remove(string(key))
+
+	if int64(len(val)) > c.max {
+		return
+	}
+
+	c.values[string(key)] = c.lru.PushFront(&cachedValue{
+		key:   string(key),
+		entry: entry,
+		val:   append([]byte{}, val...),
+	})
+
+	c.size += int64(len(val))
+
+	for c.size > c.max {
+		c.remove(c.lru.Back().Value.(*cachedValue).key)
+	}
+}
+
+// invalidate drops the cached values of the keys
+func (c *valueCache) invalidate(keys ...[]byte) {
+	if c.max == 0 {
+		return
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	for _, key := range keys {
+		c.remove(string(key))
+	}
+}
+
+func (c *valueCache) remove(key string) {
+	e, ok := c.values[key]
+	if !ok {
+		return
+	}
+
+	c.lru.Remove(e)
+
+	delete(c.values, key)
+
+	c.size -= int64(len(e.Value.(*cachedValue).val))
+}
+
+func (c *valueCache) stats() CacheStats {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return CacheStats{
+		Hits:    c.hits,
+		Misses:  c.misses,
+		Entries: c.lru.Len(),
+		Size:    c.size,
+	}
+}
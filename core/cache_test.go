@@ -0,0 +1,136 @@
+package core_test
+
+import (
+	"github.com/aneshas/gocask/core"
+	"github.com/aneshas/gocask/core/testutil"
+	caskfs "github.com/aneshas/gocask/internal/fs"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCache_Should_Serve_Written_And_Read_Values(t *testing.T) {
+	var time testutil.Time
+
+	db := openDB(t, "", caskfs.NewInMemory(), time, core.Config{MaxDataFileSize: 1024, ValueCacheSize: 1024})
+
+	defer db.Close()
+
+	_, err := db.Put([]byte("foo"), []byte("foo"))
+
+	assert.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		val, err := db.Get([]byte("foo"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("foo"), val)
+
+		// Cached value should not be modified through the returned one
+		val[0] = 'x'
+	}
+
+	assert.Equal(t, core.CacheStats{Hits: 2, Entries: 1, Size: 3}, db.CacheStats())
+	assert.Equal(t, 1.0, db.CacheStats().HitRatio())
+}
+
+func TestCache_Should_Invalidate_Overwritten_And_Deleted_Values(t *testing.T) {
+	var time testutil.Time
+
+	db := openDB(t, "", caskfs.NewInMemory(), time, core.Config{MaxDataFileSize: 1024, ValueCacheSize: 1024})
+
+	defer db.Close()
+
+	_, err := db.Put([]byte("foo"), []byte("foo"))
+
+	assert.NoError(t, err)
+
+	_, err = db.Put([]byte("foo"), []byte("bar"))
+
+	assert.NoError(t, err)
+
+	val, err := db.Get([]byte("foo"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("bar"), val)
+
+	var b core.Batch
+
+	b.Put([]byte("foo"), []byte("baz"))
+
+	assert.NoError(t, db.Write(&b))
+
+	val, err = db.Get([]byte("foo"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("baz"), val)
+
+	assert.NoError(t, db.Delete([]byte("foo")))
+
+	_, err = db.Get([]byte("foo"))
+
+	assert.ErrorIs(t, err, core.ErrKeyNotFound)
+	assert.Equal(t, core.CacheStats{Hits: 1, Misses: 1, Entries: 0, Size: 0}, db.CacheStats())
+	assert.Equal(t, 0.5, db.CacheStats().HitRatio())
+}
+
+func TestCache_Should_Evict_Least_Recently_Used_Values(t *testing.T) {
+	var time testutil.Time
+
+	db := openDB(t, "", caskfs.NewInMemory(), time, core.Config{MaxDataFileSize: 1024, ValueCacheSize: 6})
+
+	defer db.Close()
+
+	_, err := db.Put([]byte("foo"), []byte("foo"))
+
+	assert.NoError(t, err)
+
+	_, err = db.Put([]byte("bar"), []byte("bar"))
+
+	assert.NoError(t, err)
+
+	_, err = db.Get([]byte("foo"))
+
+	assert.NoError(t, err)
+
+	_, err = db.Put([]byte("baz"), []byte("baz"))
+
+	assert.NoError(t, err)
+
+	_, err = db.Put([]byte("big"), []byte("too large to be cached"))
+
+	assert.NoError(t, err)
+
+	for _, key := range []string{"foo", "baz", "bar", "big"} {
+		_, err := db.Get([]byte(key))
+
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, core.CacheStats{Hits: 3, Misses: 2, Entries: 2, Size: 6}, db.CacheStats())
+}
+
+func TestCache_Should_Be_Disabled_By_Default(t *testing.T) {
+	var time testutil.Time
+
+	db := openDB(t, "", caskfs.NewInMemory(), time, core.Config{MaxDataFileSize: 1024})
+
+	defer db.Close()
+
+	_, err := db.Put([]byte("foo"), []byte("foo"))
+
+	assert.NoError(t, err)
+
+	val, err := db.Get([]byte("foo"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("foo"), val)
+	assert.Equal(t, core.CacheStats{}, db.CacheStats())
+}
+
+func TestCache_Should_Reject_Negative_Size(t *testing.T) {
+	var time testutil.Time
+
+	_, err := core.NewDB("", caskfs.NewInMemory(), time, core.Config{ValueCacheSize: -1})
+
+	assert.ErrorIs(t, err, core.ErrInvalidConfig)
+}
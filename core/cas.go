@@ -0,0 +1,115 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+)
+
+// condition of a conditional put
+type condition uint8
+
+const (
+	condNone condition = iota
+	condAbsent
+	condValue
+	condVersion
+)
+
+// CompareAndSwap stores the new value under given key only if the currently stored value equals the expected one
+// and returns the sequence number assigned to the write. It reports ErrKeyNotFound if the key does not exist
+// and ErrConditionFailed if the stored value differs.
+// The comparison and the write are performed atomically with respect to other writes
+func (db *DB) CompareAndSwap(key, expected, val []byte) (uint64, error) {
+	return db.commitPut(&commit{
+		key:      key,
+		val:      val,
+		cond:     condValue,
+		expected: expected,
+	})
+}
+
+// PutIfAbsent stores the value under given key only if the key does not exist (or has expired)
+// and returns the sequence number assigned to the write, otherwise it reports ErrConditionFailed
+func (db *DB) PutIfAbsent(key, val []byte) (uint64, error) {
+	return db.commitPut(&commit{
+		key:  key,
+		val:  val,
+		cond: condAbsent,
+	})
+}
+
+// PutIfVersion stores the value under given key only if the version of the stored value (its sequence number
+// as returned by Put or GetWithVersion) equals the given one and returns the sequence number assigned to the write.
+// It reports ErrKeyNotFound if the key does not exist and ErrConditionFailed if the key was written in the meantime.
+// Values written in the data file formats preceding sequence numbers are of version 0
+func (db *DB) PutIfVersion(key, val []byte, version uint64) (uint64, error) {
+	return db.commitPut(&commit{
+		key:     key,
+		val:     val,
+		cond:    condVersion,
+		version: version,
+	})
+}
+
+// check reports whether the operation can be written on top of the staged ones:
+// deleted key has to exist and the condition of a conditional put has to hold
+func (db *DB) check(c *commit, pending map[string]staged) error {
+	if !c.delete && c.cond == condNone {
+		return nil
+	}
+
+	val, version, err := db.current(c.key, pending, c.cond == condValue)
+
+	if c.cond == condAbsent {
+		if errors.Is(err, ErrKeyNotFound) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return ErrConditionFailed
+	}
+
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case c.cond == condValue && !bytes.Equal(val, c.expected):
+		return ErrConditionFailed
+	case c.cond == condVersion && version != c.version:
+		return ErrConditionFailed
+	}
+
+	return nil
+}
+
+// current returns the version (and the value if requested) of the key as of the last staged operation
+// of the key or the keydir if there is none
+func (db *DB) current(key []byte, pending map[string]staged, withValue bool) ([]byte, uint64, error) {
+	if s, ok := pending[string(key)]; ok {
+		if s.c.delete {
+			return nil, 0, ErrKeyNotFound
+		}
+
+		return s.c.val, s.h.Seq, nil
+	}
+
+	ke, err := db.lookup(key)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !withValue {
+		return nil, ke.Seq, nil
+	}
+
+	val, err := db.readValue(key, ke)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return val, ke.Seq, nil
+}
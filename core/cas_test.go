@@ -0,0 +1,186 @@
+package core_test
+
+import (
+	"github.com/aneshas/gocask/core"
+	"github.com/aneshas/gocask/core/testutil"
+	caskfs "github.com/aneshas/gocask/internal/fs"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"sync"
+	"testing"
+	gotime "time"
+)
+
+func TestCAS_Should_Swap_Only_Expected_Value(t *testing.T) {
+	var time testutil.Time
+
+	db := openDB(t, "", caskfs.NewInMemory(), time, core.Config{MaxDataFileSize: 1024})
+
+	defer db.Close()
+
+	_, err := db.CompareAndSwap([]byte("foo"), []byte("bar"), []byte("baz"))
+
+	assert.ErrorIs(t, err, core.ErrKeyNotFound)
+
+	_, err = db.Put([]byte("foo"), []byte("bar"))
+
+	assert.NoError(t, err)
+
+	_, err = db.CompareAndSwap([]byte("foo"), []byte("bar"), []byte("baz"))
+
+	assert.NoError(t, err)
+
+	_, err = db.CompareAndSwap([]byte("foo"), []byte("bar"), []byte("qux"))
+
+	assert.ErrorIs(t, err, core.ErrConditionFailed)
+
+	val, err := db.Get([]byte("foo"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("baz"), val)
+}
+
+func TestCAS_Should_Put_Only_Absent_Or_Expired_Keys(t *testing.T) {
+	clock := &testutil.Clock{Now: 100}
+
+	db := openDB(t, "", caskfs.NewInMemory(), clock, core.Config{MaxDataFileSize: 1024})
+
+	defer db.Close()
+
+	_, err := db.PutIfAbsent([]byte("foo"), []byte("bar"))
+
+	assert.NoError(t, err)
+
+	_, err = db.PutIfAbsent([]byte("foo"), []byte("baz"))
+
+	assert.ErrorIs(t, err, core.ErrConditionFailed)
+
+	_, err = db.PutWithTTL([]byte("foo"), []byte("bar"), 10*gotime.Second)
+
+	assert.NoError(t, err)
+
+	clock.Advance(10)
+
+	_, err = db.PutIfAbsent([]byte("foo"), []byte("baz"))
+
+	assert.NoError(t, err)
+
+	val, err := db.Get([]byte("foo"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("baz"), val)
+}
+
+func TestCAS_Should_Put_Only_Expected_Version(t *testing.T) {
+	var time testutil.Time
+
+	db := openDB(t, "", caskfs.NewInMemory(), time, core.Config{MaxDataFileSize: 1024})
+
+	defer db.Close()
+
+	_, err := db.PutIfVersion([]byte("foo"), []byte("bar"), 1)
+
+	assert.ErrorIs(t, err, core.ErrKeyNotFound)
+
+	_, err = db.Put([]byte("foo"), []byte("bar"))
+
+	assert.NoError(t, err)
+
+	_, version, err := db.GetWithVersion([]byte("foo"))
+
+	assert.NoError(t, err)
+
+	_, err = db.Put([]byte("foo"), []byte("baz"))
+
+	assert.NoError(t, err)
+
+	_, err = db.PutIfVersion([]byte("foo"), []byte("qux"), version)
+
+	assert.ErrorIs(t, err, core.ErrConditionFailed)
+
+	_, version, err = db.GetWithVersion([]byte("foo"))
+
+	assert.NoError(t, err)
+
+	_, err = db.PutIfVersion([]byte("foo"), []byte("qux"), version)
+
+	assert.NoError(t, err)
+
+	val, newVersion, err := db.GetWithVersion([]byte("foo"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("qux"), val)
+	assert.NotEqual(t, version, newVersion)
+}
+
+func TestCAS_Should_Check_Conditions_Against_Operations_Of_The_Same_Group(t *testing.T) {
+	bw := newBlockedWrites()
+	db := openBlocked(t, bw)
+
+	defer db.Close()
+
+	errs := queueBehindBlockedWrite(t, db, bw, []func() error{
+		func() error { return putErr(db.PutIfAbsent([]byte("foo"), []byte("foo"))) },
+		func() error { return putErr(db.PutIfAbsent([]byte("foo"), []byte("bar"))) },
+		func() error { return putErr(db.CompareAndSwap([]byte("foo"), []byte("foo"), []byte("baz"))) },
+		func() error { return putErr(db.CompareAndSwap([]byte("foo"), []byte("foo"), []byte("qux"))) },
+	})
+
+	assert.NoError(t, errs[0])
+	assert.ErrorIs(t, errs[1], core.ErrConditionFailed)
+	assert.NoError(t, errs[2])
+	assert.ErrorIs(t, errs[3], core.ErrConditionFailed)
+
+	assert.Equal(t, 2, bw.count())
+
+	val, err := db.Get([]byte("foo"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("baz"), val)
+}
+
+func TestCAS_Should_Not_Lose_Concurrent_Increments(t *testing.T) {
+	var time testutil.Time
+
+	db := openDB(t, "", caskfs.NewInMemory(), time, core.Config{MaxDataFileSize: 1024})
+
+	defer db.Close()
+
+	_, err := db.Put([]byte("counter"), []byte("0"))
+
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 25; {
+				val, err := db.Get([]byte("counter"))
+
+				assert.NoError(t, err)
+
+				n, _ := strconv.Atoi(string(val))
+
+				_, err = db.CompareAndSwap([]byte("counter"), val, []byte(strconv.Itoa(n+1)))
+				if err == nil {
+					j++
+
+					continue
+				}
+
+				assert.ErrorIs(t, err, core.ErrConditionFailed)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	val, err := db.Get([]byte("counter"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("100"), val)
+}
@@ -0,0 +1,237 @@
+package core
+
+import (
+	"sync"
+)
+
+// maxGroupSize bounds the total size of the entries written by a single group commit
+const maxGroupSize = 1024 * 1024
+
+// commit is a Put or Delete waiting to be written by a group commit
+type commit struct {
+	key, val []byte
+	ttl      uint32
+	delete   bool
+
+	// cond is checked (against expected value or version) right before a conditional put is staged
+	cond     condition
+	expected []byte
+	version  uint64
+
+	// seq is the sequence number assigned to the written entry
+	seq  uint64
+	err  error
+	lead bool
+	wake chan struct{}
+}
+
+// committer coalesces concurrent Put and Delete calls into group commits. The first writer to arrive
+// leads the group: it writes the entries of all writers queued so far with a single write (and sync),
+// wakes them up with their results and hands the leadership over to the next writer in the queue
+type committer struct {
+	m       sync.Mutex
+	queue   []*commit
+	leading bool
+}
+
+// staged is an entry appended to the group commit buffer whose keydir update is applied once it's written
+type staged struct {
+	c *commit
+	h header
+}
+
+// commit queues the operation and waits until it's written by the group leader (or leads the group itself)
+func (db *DB) commit(c *commit) error {
+	c.wake = make(chan struct{}, 1)
+
+	gc := &db.gc
+
+	gc.m.Lock()
+
+	gc.queue = append(gc.queue, c)
+
+	lead := !gc.leading
+
+	gc.leading = true
+
+	gc.m.Unlock()
+
+	if !lead {
+		<-c.wake
+
+		if !c.lead {
+			return c.err
+		}
+	}
+
+	group := gc.next()
+
+	db.writeGroup(group)
+
+	gc.m.Lock()
+
+	if len(gc.queue) > 0 {
+		gc.queue[0].lead = true
+		gc.queue[0].wake <- struct{}{}
+	} else {
+		gc.leading = false
+	}
+
+	gc.m.Unlock()
+
+	for _, o := range group {
+		if o != c {
+			o.wake <- struct{}{}
+		}
+	}
+
+	return c.err
+}
+
+// next dequeues the operations of the next group (up to maxGroupSize bytes of entries)
+func (gc *committer) next() []*commit {
+	gc.m.Lock()
+	defer gc.m.Unlock()
+
+	var size int
+
+	n := 0
+
+	for n < len(gc.queue) {
+		size += int(headerSize) + len(gc.queue[n].key) + len(gc.queue[n].val)
+
+		if n > 0 && size > maxGroupSize {
+			break
+		}
+
+		n++
+	}
+
+	group := gc.queue[:n:n]
+
+	gc.queue = append([]*commit(nil), gc.queue[n:]...)
+
+	return group
+}
+
+// writeGroup writes the operations in order and sets their results.
+// Operations which failed validation (eg. delete of a missing key) are not written and do not fail the rest
+func (db *DB) writeGroup(group []*commit) {
+	db.m.RLock()
+	defer db.m.RUnlock()
+
+	db.wm.Lock()
+	defer db.wm.Unlock()
+
+	now := db.time.NowUnix()
+
+	var (
+		buf   []byte
+		stage []staged
+
+		// pending holds the last staged operation of each key
+		pending = map[string]staged{}
+	)
+
+	flush := func() error {
+		if len(buf) == 0 {
+			return nil
+		}
+
+		err := db.writeEntries(buf)
+
+		db.applyStaged(stage, err)
+
+		buf, stage, pending = nil, nil, map[string]staged{}
+
+		return err
+	}
+
+	for _, c := range group {
+		var h header
+
+		// Sequence number is taken only once the operation is staged
+		seq := db.seq + 1
+
+		if c.delete {
+			h = newKVHeader(now, 0, seq, nil, c.key)
+		} else {
+			var expiry uint32
+
+			if c.ttl > 0 {
+				expiry, c.err = expiryAt(now, c.ttl)
+				if c.err != nil {
+					continue
+				}
+			}
+
+			h = newKVHeader(now, expiry, seq, c.key, c.val)
+		}
+
+		if db.file.Size()+int64(len(buf))+int64(h.entrySize()) > db.cfg.MaxDataFileSize {
+			// Staged operations are failed by flush upon error and do not affect this one
+			_ = flush()
+
+			c.err = db.rotate()
+			if c.err != nil {
+				continue
+			}
+		}
+
+		c.err = db.check(c, pending)
+		if c.err != nil {
+			continue
+		}
+
+		if c.delete {
+			buf = append(buf, serializeEntry(h, nil, c.key)...)
+		} else {
+			buf = append(buf, serializeEntry(h, c.key, c.val)...)
+		}
+
+		db.seq = seq
+
+		s := staged{c: c, h: h}
+
+		stage = append(stage, s)
+		pending[string(c.key)] = s
+	}
+
+	_ = flush()
+}
+
+// applyStaged updates the keydir (and the value cache) with the written entries and notifies the watchers,
+// or reports the write error
+func (db *DB) applyStaged(stage []staged, err error) {
+	for _, s := range stage {
+		s.c.err = err
+
+		if err != nil {
+			continue
+		}
+
+		s.c.seq = s.h.Seq
+
+		if s.c.delete {
+			db.kd.unset(s.c.key, s.h)
+			db.cache.invalidate(s.c.key)
+
+			continue
+		}
+
+		db.kd.set(s.c.key, s.h, db.file.Name())
+
+		ke, _ := db.kd.get(s.c.key)
+
+		db.cache.set(s.c.key, ke, s.c.val)
+	}
+
+	if err != nil {
+		return
+	}
+
+	// Watchers are notified once the writes can be read, still under the append lock so that events are in order
+	for _, s := range stage {
+		db.watchers.notify(s.c.key, s.h)
+	}
+}
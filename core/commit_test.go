@@ -0,0 +1,368 @@
+package core_test
+
+import (
+	"fmt"
+	"github.com/aneshas/gocask/core"
+	"github.com/aneshas/gocask/core/testutil"
+	caskfs "github.com/aneshas/gocask/internal/fs"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"sync"
+	"testing"
+	gotime "time"
+)
+
+// blockedWrites holds the first write to a data file until it's released and counts all writes
+type blockedWrites struct {
+	m       sync.Mutex
+	writes  int
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockedWrites() *blockedWrites {
+	return &blockedWrites{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+}
+
+func (b *blockedWrites) hook(_ []byte) {
+	b.m.Lock()
+	b.writes++
+	first := b.writes == 1
+	b.m.Unlock()
+
+	if first {
+		close(b.started)
+		<-b.release
+	}
+}
+
+func (b *blockedWrites) count() int {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	return b.writes
+}
+
+// queueBehindBlockedWrite starts the operations one by one while the first write to the data file is blocked,
+// each once the previous one is queued, so that they're written by the same group commit in the given order.
+// Results of the operations are returned in order once the blocked write is released
+func queueBehindBlockedWrite(t *testing.T, db *core.DB, bw *blockedWrites, ops []func() error) []error {
+	go func() {
+		_, err := db.Put([]byte("first"), []byte("first"))
+
+		assert.NoError(t, err)
+	}()
+
+	<-bw.started
+
+	results := make([]chan error, len(ops))
+
+	for i, op := range ops {
+		results[i] = make(chan error, 1)
+
+		go func(op func() error, res chan error) {
+			res <- op()
+		}(op, results[i])
+
+		awaitQueued(t, db, i+1)
+	}
+
+	close(bw.release)
+
+	errs := make([]error, len(ops))
+
+	for i, res := range results {
+		errs[i] = <-res
+	}
+
+	return errs
+}
+
+// awaitQueued waits until n writes are queued for the group commit
+func awaitQueued(t *testing.T, db *core.DB, n int) {
+	assert.Eventually(t, func() bool {
+		return core.QueuedCommits(db) == n
+	}, 5*gotime.Second, gotime.Millisecond)
+}
+
+// putErr drops the sequence number returned by a put
+func putErr(_ uint64, err error) error {
+	return err
+}
+
+func openBlocked(t *testing.T, bw *blockedWrites) *core.DB {
+	var time testutil.Time
+
+	cfg := core.DefaultConfig
+	cfg.SyncPolicy = core.SyncAlways
+
+	fs := testutil.NewInMemory(caskfs.NewInMemory())
+
+	db := openDB(t, "mydb", fs, time, cfg)
+
+	// Data file header written upon opening is not counted
+	fs.WithWriteHook(bw.hook)
+
+	return db
+}
+
+func TestGroupCommit_Should_Coalesce_Concurrent_Writes(t *testing.T) {
+	bw := newBlockedWrites()
+	db := openBlocked(t, bw)
+
+	defer db.Close()
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		_, err := db.Put([]byte("first"), []byte("first"))
+
+		assert.NoError(t, err)
+	}()
+
+	<-bw.started
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			key := []byte(fmt.Sprintf("key_%d", i))
+
+			_, err := db.Put(key, key)
+
+			assert.NoError(t, err)
+		}(i)
+	}
+
+	// Writers queue up behind the blocked write
+	awaitQueued(t, db, 10)
+
+	close(bw.release)
+
+	wg.Wait()
+
+	assert.Equal(t, 2, bw.count())
+
+	for i := 0; i < 10; i++ {
+		key := []byte(fmt.Sprintf("key_%d", i))
+
+		val, err := db.Get(key)
+
+		assert.NoError(t, err)
+		assert.Equal(t, key, val)
+	}
+}
+
+func TestGroupCommit_Should_Report_Errors_Per_Caller_In_Order(t *testing.T) {
+	bw := newBlockedWrites()
+	db := openBlocked(t, bw)
+
+	defer db.Close()
+
+	errs := queueBehindBlockedWrite(t, db, bw, []func() error{
+		func() error { return db.Delete([]byte("foo")) },
+		func() error { return putErr(db.Put([]byte("foo"), []byte("foo"))) },
+		func() error { return db.Delete([]byte("foo")) },
+		func() error { return db.Delete([]byte("foo")) },
+	})
+
+	assert.ErrorIs(t, errs[0], core.ErrKeyNotFound)
+	assert.NoError(t, errs[1])
+	assert.NoError(t, errs[2])
+	assert.ErrorIs(t, errs[3], core.ErrKeyNotFound)
+
+	assert.Equal(t, 2, bw.count())
+
+	_, err := db.Get([]byte("foo"))
+
+	assert.ErrorIs(t, err, core.ErrKeyNotFound)
+}
+
+func TestGroupCommit_Should_Rotate_Data_Files_Within_A_Group(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_commit")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	var time testutil.Time
+
+	cfg := core.Config{MaxDataFileSize: 256, SyncPolicy: core.SyncAlways}
+
+	db, err := core.NewDB(dbPath, caskfs.NewDisk(), time, cfg)
+
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				key := []byte(fmt.Sprintf("key_%d_%d", i, j))
+
+				_, err := db.Put(key, key)
+
+				assert.NoError(t, err)
+
+				if j%5 == 0 {
+					assert.NoError(t, db.Delete(key))
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	assert.NoError(t, db.Close())
+
+	db, err = core.NewDB(dbPath, caskfs.NewDisk(), time, cfg)
+
+	assert.NoError(t, err)
+
+	defer db.Close()
+
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 50; j++ {
+			key := []byte(fmt.Sprintf("key_%d_%d", i, j))
+
+			val, err := db.Get(key)
+
+			if j%5 == 0 {
+				assert.ErrorIs(t, err, core.ErrKeyNotFound)
+
+				continue
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, key, val)
+		}
+	}
+}
+
+func TestShould_Serve_Reads_While_Write_Is_In_Progress(t *testing.T) {
+	bw := newBlockedWrites()
+	db := openBlocked(t, bw)
+
+	defer db.Close()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		_, err := db.Put([]byte("bar"), []byte("bar"))
+
+		assert.NoError(t, err)
+	}()
+
+	<-bw.started
+
+	// Get does not wait for the write holding the append lock
+	_, err := db.Get([]byte("bar"))
+
+	assert.ErrorIs(t, err, core.ErrKeyNotFound)
+
+	close(bw.release)
+
+	<-done
+
+	val, err := db.Get([]byte("bar"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("bar"), val)
+}
+
+func TestShould_Serve_Concurrent_Reads_Writes_Snapshots_And_Merges(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_commit")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	var time testutil.Time
+
+	db, err := core.NewDB(dbPath, caskfs.NewDisk(), time, core.Config{MaxDataFileSize: 512, ValueCacheSize: 1024})
+
+	assert.NoError(t, err)
+
+	defer db.Close()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 200; j++ {
+				key := []byte(fmt.Sprintf("key_%d", j%20))
+
+				_, err := db.Put(key, key)
+
+				assert.NoError(t, err)
+			}
+		}(i)
+
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 200; j++ {
+				key := []byte(fmt.Sprintf("key_%d", j%20))
+
+				val, err := db.Get(key)
+				if err == nil {
+					assert.Equal(t, key, val)
+				} else {
+					assert.ErrorIs(t, err, core.ErrKeyNotFound)
+				}
+
+				if j%50 != 0 {
+					continue
+				}
+
+				s := db.Snapshot()
+
+				assert.NoError(t, s.Fold(func(key, val []byte) error {
+					assert.Equal(t, key, val)
+
+					return nil
+				}))
+
+				assert.NoError(t, s.Release())
+				assert.NoError(t, db.Fold(func(key, val []byte) error {
+					assert.Equal(t, key, val)
+
+					return nil
+				}))
+			}
+		}(i)
+	}
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for j := 0; j < 5; j++ {
+			assert.NoError(t, db.Merge())
+		}
+	}()
+
+	wg.Wait()
+
+	assert.Len(t, db.Keys(), 20)
+}
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"github.com/aneshas/gocask/internal/crc"
 	"io"
+	"math"
 	"path"
 	"sync"
+	"time"
 )
 
 var (
@@ -19,14 +21,38 @@ var (
 	// that write was successful but the entry is corrupted and the operation should be retried
 	ErrPartialWrite = errors.New("gocask: key/value pair not fully written")
 
-	// ErrCRCFailed is thrown upon reading a corrupted value
-	ErrCRCFailed = errors.New("gocask: crc check failed for db entry (value is corrupted)")
+	// ErrCRCFailed is thrown upon reading a corrupted entry
+	ErrCRCFailed = errors.New("gocask: crc check failed for db entry (entry is corrupted)")
 
 	// ErrInvalidKey is thrown when attempting Get, Put or Delete with an invalid key
 	ErrInvalidKey = errors.New("gocask: key should not be empty or nil")
 
 	// ErrInvalidValue is thrown when attempting to store a nil value
 	ErrInvalidValue = errors.New("gocask: value should not be nil")
+
+	// ErrReadOnly is thrown when attempting to modify a database opened in read-only mode
+	ErrReadOnly = errors.New("gocask: database is opened in read-only mode")
+
+	// ErrDatabaseLocked is thrown when opening a database which is already opened by another process
+	ErrDatabaseLocked = errors.New("gocask: database is locked by another process")
+
+	// ErrInvalidTTL is thrown when attempting to store a value with a non-positive ttl
+	// or a ttl which expires past the largest (32-bit) expiry timestamp
+	ErrInvalidTTL = errors.New("gocask: ttl should be positive and expire before 2106")
+
+	// ErrEntryTooLarge is thrown when attempting to store a key, value or batch larger than the data file format can address.
+	// Keys are limited to 512MiB and values and batches to 4GiB
+	ErrEntryTooLarge = errors.New("gocask: key/value pair is too large")
+
+	// ErrInvalidConfig is thrown when opening a database with a configuration which is not supported
+	ErrInvalidConfig = errors.New("gocask: invalid config")
+
+	// ErrConditionFailed is thrown when the stored value (or version) of the key does not match the one
+	// expected by a conditional put, or the key already exists upon PutIfAbsent
+	ErrConditionFailed = errors.New("gocask: condition not met")
+
+	// ErrClosed is thrown when attempting to watch a database which is closed
+	ErrClosed = errors.New("gocask: database is closed")
 )
 
 // InMemoryDB represents a magic value which can be used instead of db path
@@ -36,8 +62,12 @@ const InMemoryDB = "in:mem:db"
 // FS represents a file system interface
 type FS interface {
 	// Open should open the active data file for the given db path
+	// and lock the db path for exclusive use
 	Open(string) (File, error)
 
+	// Close should release the resources acquired by Open (eg. the lock) for the given db path
+	Close(string) error
+
 	// Rotate should generate and open new data file for the given db path
 	Rotate(string) (File, error)
 
@@ -46,6 +76,27 @@ type FS interface {
 
 	// ReadFileAt should read a chunk of named path data file at the given offset
 	ReadFileAt(string, string, []byte, int64) (int, error)
+
+	// Files should list names of all data files for the given db path
+	// ordered from the oldest to the newest one
+	Files(string) ([]string, error)
+
+	// CreateMergeFile should create a new data file which will be ordered right before
+	// the named active data file. The file should be hidden from Walk until it is committed
+	CreateMergeFile(string, string) (File, error)
+
+	// CommitMerge should atomically make the named merged data files visible
+	CommitMerge(string, []string) error
+
+	// Remove should remove the named data file (along with its hint file) for the given db path
+	Remove(string, string) error
+
+	// CreateHintFile should create a hint file for the named data file.
+	// The hint file should become visible only once it is closed
+	CreateHintFile(string, string) (File, error)
+
+	// OpenHintFile should open the hint file of the named data file for reading
+	OpenHintFile(string, string) (File, error)
 }
 
 // File represents a single fs data file
@@ -54,6 +105,12 @@ type File interface {
 
 	Name() string
 	Size() int64
+
+	// Sync should commit the written data to durable storage
+	Sync() error
+
+	// Truncate should change the size of the file. It is used for dropping a torn tail upon startup
+	Truncate(int64) error
 }
 
 // Time represents time provider
@@ -71,7 +128,35 @@ type DB struct {
 	file File
 	path string
 	kd   *keyDir
-	m    sync.RWMutex
+	mm   sync.Mutex
+
+	// m is held shared by reads and writes, so that they do not wait for each other (keydir shards are
+	// locked on their own), and it's held exclusively by merge commit, snapshots, refresh and Close
+	m sync.RWMutex
+
+	// wm serializes appends to the active data file
+	wm sync.Mutex
+
+	// seq is the last sequence number assigned to an entry (or read upon startup). It's guarded by wm
+	seq uint64
+
+	done chan struct{}
+	wg   sync.WaitGroup
+	tail tail
+
+	snaps   map[*Snapshot]struct{}
+	retired map[string]*retirement
+
+	// valueCRC holds data files written in the older format whose entries carry crc of the value only
+	valueCRC map[string]bool
+
+	// versioned is set once a data file with the file header is read
+	versioned bool
+
+	scrub    scrubber
+	cache    *valueCache
+	gc       committer
+	watchers watchers
 }
 
 // DefaultConfig represents default gocask config
@@ -82,14 +167,66 @@ var DefaultConfig = Config{
 
 // Config represents gocask config
 type Config struct {
+	// MaxDataFileSize is the size after which the active data file is rotated.
+	// It should not be negative
 	MaxDataFileSize int64
 	DataDir         string
+	SyncPolicy      SyncPolicy
+
+	// ReadOnly opens the database without locking it or creating an active data file
+	// so that it can be shared with a process writing to it
+	ReadOnly bool
+
+	// Strict refuses to open the database with ErrTornTail if a data file ends with an incomplete
+	// or corrupted entry (eg. due to a crash during write) instead of truncating the entry
+	Strict bool
+
+	// Logger logs recovery actions taken upon startup (eg. truncating a torn entry). Nothing is logged if nil
+	Logger Logger
+
+	// Scrub configures background scrubbing of immutable data files (disabled by default).
+	// Databases opened in read-only mode are not scrubbed
+	Scrub ScrubPolicy
+
+	// MMap reads immutable data files through read-only memory mappings instead of a syscall per read.
+	// It selects the file system of databases opened by gocask.Open (FS passed to NewDB is used as is)
+	MMap bool
+
+	// ValueCacheSize is the max total size (in bytes) of the values cached in memory by Get and Put.
+	// Values are not cached if zero (default) and it should not be negative
+	ValueCacheSize int64
+}
+
+func (cfg Config) validate() error {
+	if cfg.MaxDataFileSize < 0 {
+		return fmt.Errorf("%w: max data file size should not be negative", ErrInvalidConfig)
+	}
+
+	if cfg.ValueCacheSize < 0 {
+		return fmt.Errorf("%w: value cache size should not be negative", ErrInvalidConfig)
+	}
+
+	err := cfg.Scrub.validate()
+	if err != nil {
+		return err
+	}
+
+	return cfg.SyncPolicy.validate()
 }
 
 // NewDB instantiates new db with provided FS as storage mechanism
 func NewDB(dbpath string, fs FS, time Time, cfg Config) (*DB, error) {
+	err := cfg.validate()
+	if err != nil {
+		return nil, err
+	}
+
 	dbpath = path.Join(cfg.DataDir, dbpath)
 
+	if cfg.ReadOnly {
+		return openReadOnly(dbpath, fs, time, cfg)
+	}
+
 	f, err := fs.Open(dbpath)
 	if err != nil {
 		return nil, err
@@ -102,113 +239,369 @@ func NewDB(dbpath string, fs FS, time Time, cfg Config) (*DB, error) {
 		file: f,
 		path: dbpath,
 		kd:   newKeyDir(),
+
+		snaps:   map[*Snapshot]struct{}{},
+		retired: map[string]*retirement{},
+
+		valueCRC: map[string]bool{},
+		cache:    newValueCache(cfg.ValueCacheSize),
 	}
 
-	return &caskDB, caskDB.init(f)
-}
+	err = caskDB.init(f)
+	if err != nil {
+		_ = caskDB.file.Close()
+		_ = fs.Close(dbpath)
 
-func (db *DB) init(activeFile File) error {
-	return db.fs.Walk(db.path, func(file File) error {
-		err := db.walkFile(file)
-		if err != nil {
-			return err
-		}
+		return nil, err
+	}
 
-		if file.Name() != activeFile.Name() {
-			db.kd.resetOffset()
-		}
+	caskDB.startSync()
+	caskDB.startScrub()
 
-		return nil
-	})
+	return &caskDB, nil
 }
 
-func (db *DB) walkFile(file File) error {
+func (db *DB) init(activeFile File) error {
 	var (
-		r    = bufio.NewReader(file)
-		name = file.Name()
+		empty, rotate bool
+		torn          *tornTailError
 	)
 
-	for {
-		err := db.readEntry(r, name)
-		if err != nil {
-			if errors.Is(err, io.EOF) {
-				break
+	err := db.fs.Walk(db.path, func(file File) error {
+		if file.Name() == activeFile.Name() {
+			fh, err := db.walkFile(file)
+			if errors.As(err, &torn) {
+				err = nil
 			}
 
-			return fmt.Errorf("gocask: startup error: %w", err)
+			switch {
+			case errors.Is(err, errEmptyFile):
+				empty = true
+			case err != nil:
+				return err
+			default:
+				// New writes always go to a data file of the current format version
+				rotate = fh.Version != formatVersion
+			}
+
+			return nil
+		}
+
+		defer db.kd.resetOffset()
+
+		if db.loadHints(file.Name()) {
+			return nil
+		}
+
+		_, err := db.walkFile(file)
+
+		var tt *tornTailError
+
+		if errors.As(err, &tt) {
+			return db.skipTornTail(tt)
+		}
+
+		if errors.Is(err, errEmptyFile) {
+			return nil
+		}
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if torn != nil {
+		err = db.truncateTornTail(torn)
+		if err != nil {
+			return err
 		}
+
+		empty = torn.offset == 0
+	}
+
+	if empty {
+		return db.writeFileHeader()
+	}
+
+	if rotate {
+		return db.rotate()
 	}
 
 	return nil
 }
 
-func (db *DB) readEntry(r *bufio.Reader, file string) error {
-	h, err := parseHeader(r)
+// loadHints populates keydir from the hint file of an immutable data file
+// and reports whether a valid hint file was found
+func (db *DB) loadHints(dataFile string) bool {
+	f, err := db.fs.OpenHintFile(db.path, dataFile)
 	if err != nil {
-		return err
+		return false
 	}
 
-	keySize := h.KeySize
+	defer f.Close()
 
-	if h.isTombstone() {
-		keySize = h.ValueSize
+	hints, err := readHints(f, dataFile)
+	if err != nil {
+		return false
 	}
 
-	key := make([]byte, keySize)
+	fh, err := db.readFileHeader(dataFile)
+	if err != nil {
+		return false
+	}
 
-	_, err = io.ReadFull(r, key)
+	err = db.setFormat(dataFile, fh)
 	if err != nil {
-		return err
+		return false
 	}
 
-	if h.isTombstone() {
-		db.kd.unset(key)
+	for _, h := range hints {
+		db.kd.setEntry(h.key, h.entry)
+		db.observeSeq(h.entry.Seq)
+	}
 
-		return nil
+	return true
+}
+
+// walkFile populates keydir from the data file and returns its file header
+func (db *DB) walkFile(file File) (fileHeader, error) {
+	r := bufio.NewReader(file)
+
+	fh, err := readFileHeader(r)
+	if err != nil {
+		if errors.Is(err, errTornFileHeader) {
+			return fh, &tornTailError{
+				file: file.Name(),
+				err:  err,
+			}
+		}
+
+		if errors.Is(err, errEmptyFile) {
+			return fh, err
+		}
+
+		return fh, fmt.Errorf("gocask: startup error: %w", err)
 	}
 
-	_, err = r.Discard(int(h.ValueSize))
+	db.kd.advanceOffsetBy(uint64(fh.size()))
+
+	err = db.setFormat(file.Name(), fh)
 	if err != nil {
-		return err
+		return fh, fmt.Errorf("gocask: startup error: %w", err)
 	}
 
-	db.kd.set(key, h, file)
+	switch fh.Version {
+	case formatVersion0, formatVersion1, formatVersion2, formatVersion3:
+		// Entries are laid out the same way in all versions (sequence numbers are optional header fields)
+		return fh, db.walkEntries(r, file)
+	}
 
-	return err
+	return fh, fmt.Errorf("gocask: startup error: %w: version %d", ErrUnsupportedFormat, fh.Version)
 }
 
-func (db *DB) Close() error {
-	return db.file.Close()
+// walkEntries reads entries until the end of the data file. Entry which was not completely written
+// (including the last entry failing the crc check) is reported with tornTailError,
+// unless valid entries follow it (see checkTornTail)
+func (db *DB) walkEntries(r *bufio.Reader, file File) error {
+	offset, err := db.readEntries(r, file.Name())
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+
+	if isTorn(err) {
+		err = db.checkTornTail(file.Name(), offset)
+		if err != nil {
+			return fmt.Errorf("gocask: startup error: %w", err)
+		}
+
+		return &tornTailError{
+			file:   file.Name(),
+			offset: offset,
+			err:    err,
+		}
+	}
+
+	return fmt.Errorf("gocask: startup error: %w", err)
 }
 
-// Put stores the value under given key
-func (db *DB) Put(key, val []byte) error {
-	if len(key) == 0 {
-		return ErrInvalidKey
+// readEntries reads entries of the named data file into the keydir until it fails to read one.
+// Keydir offset is left at the start of the entry which failed, which is returned along with the error
+func (db *DB) readEntries(r *bufio.Reader, file string) (uint64, error) {
+	er := newEntryReader(r, db.kd.lastOffset, db.valueCRC[file], false)
+
+	for {
+		offset := db.kd.lastOffset
+
+		e, err := er.next()
+
+		// Corrupted values of the entries written in the older format are reported upon read,
+		// since the rest of the entry is not covered by crc
+		if err != nil && !errors.Is(err, errCorruptedValue) {
+			db.kd.lastOffset = offset
+
+			return offset, err
+		}
+
+		// Entries of a batch follow its header
+		db.kd.lastOffset = e.offset
+
+		if e.h.isTombstone() {
+			db.kd.unset(e.target(), e.h)
+		} else {
+			db.kd.set(e.key, e.h, file)
+		}
+
+		db.observeSeq(e.h.Seq)
+	}
+}
+
+// lastSeq returns the last sequence number assigned to an entry
+func (db *DB) lastSeq() uint64 {
+	db.wm.Lock()
+	defer db.wm.Unlock()
+
+	return db.seq
+}
+
+// observeSeq advances the last sequence number past the one read from a data or hint file
+func (db *DB) observeSeq(seq uint64) {
+	if seq > db.seq {
+		db.seq = seq
 	}
+}
 
-	if val == nil {
-		return ErrInvalidValue
+// Close flushes (unless SyncNever policy is used) and closes the active data file
+// and releases the database lock. Open snapshots are released and the channels of watches are closed.
+// Database opened in read-only mode holds no lock, so closing it only releases the data files opened for reading
+func (db *DB) Close() error {
+	if db.cfg.ReadOnly {
+		return db.fs.Close(db.path)
 	}
 
+	db.watchers.close()
+	db.stopBackground()
+
 	db.m.Lock()
 	defer db.m.Unlock()
 
-	h := newKVHeader(db.time.NowUnix(), key, val)
+	for s := range db.snaps {
+		s.released = true
+		s.pins = nil
+
+		delete(db.snaps, s)
+	}
 
-	err := db.rotateDataFile(int64(h.entrySize()))
+	err := db.removeAllRetired()
 	if err != nil {
+		_ = db.closeFile()
+		_ = db.fs.Close(db.path)
+
 		return err
 	}
 
-	err = db.writeKeyVal(h, key, val)
+	err = db.closeFile()
 	if err != nil {
+		_ = db.fs.Close(db.path)
+
 		return err
 	}
 
-	db.kd.set(key, h, db.file.Name())
+	return db.fs.Close(db.path)
+}
 
-	return nil
+func (db *DB) closeFile() error {
+	if db.cfg.SyncPolicy.mode != syncNever {
+		err := db.file.Sync()
+		if err != nil {
+			_ = db.file.Close()
+
+			return err
+		}
+	}
+
+	return db.file.Close()
+}
+
+// Put stores the value under given key and returns the sequence number assigned to the write
+func (db *DB) Put(key, val []byte) (uint64, error) {
+	return db.put(key, val, 0)
+}
+
+// PutWithTTL stores the value under given key which expires after the given ttl
+// and returns the sequence number assigned to the write.
+// Expired keys are reported as not found and are physically removed upon merge.
+// TTL is rounded up to a whole second
+func (db *DB) PutWithTTL(key, val []byte, ttl time.Duration) (uint64, error) {
+	secs, ok := ttlSeconds(ttl)
+	if !ok {
+		return 0, ErrInvalidTTL
+	}
+
+	return db.put(key, val, secs)
+}
+
+// ttlSeconds rounds the ttl up to a whole second and reports whether it's positive and fits the expiry timestamp
+func ttlSeconds(ttl time.Duration) (uint32, bool) {
+	if ttl <= 0 {
+		return 0, false
+	}
+
+	secs := ttl / time.Second
+
+	if ttl%time.Second != 0 {
+		secs++
+	}
+
+	if secs > math.MaxUint32 {
+		return 0, false
+	}
+
+	return uint32(secs), true
+}
+
+// expiryAt returns the expiry timestamp of a value written at t with the given ttl (in seconds)
+func expiryAt(t, ttl uint32) (uint32, error) {
+	if ttl > math.MaxUint32-t {
+		return 0, ErrInvalidTTL
+	}
+
+	return t + ttl, nil
+}
+
+func (db *DB) put(key, val []byte, ttl uint32) (uint64, error) {
+	return db.commitPut(&commit{
+		key: key,
+		val: val,
+		ttl: ttl,
+	})
+}
+
+// commitPut validates the (possibly conditional) put, commits it and returns its sequence number
+func (db *DB) commitPut(c *commit) (uint64, error) {
+	if db.cfg.ReadOnly {
+		return 0, ErrReadOnly
+	}
+
+	if len(c.key) == 0 {
+		return 0, ErrInvalidKey
+	}
+
+	if c.val == nil {
+		return 0, ErrInvalidValue
+	}
+
+	err := checkEntrySize(c.key, c.val)
+	if err != nil {
+		return 0, err
+	}
+
+	err = db.commit(c)
+	if err != nil {
+		return 0, err
+	}
+
+	return c.seq, nil
 }
 
 func (db *DB) rotateDataFile(entrySz int64) error {
@@ -216,61 +609,91 @@ func (db *DB) rotateDataFile(entrySz int64) error {
 		return nil
 	}
 
-	err := db.file.Close()
+	return db.rotate()
+}
+
+func (db *DB) rotate() error {
+	err := db.closeFile()
 	if err != nil {
 		return err
 	}
 
-	db.file, err = db.fs.Rotate(db.path)
+	// Active data file is replaced only once the new one is opened, so that it is never left nil upon failure
+	f, err := db.fs.Rotate(db.path)
 	if err != nil {
 		return err
 	}
 
+	db.file = f
+
 	db.kd.resetOffset()
 
+	return db.writeFileHeader()
+}
+
+func (db *DB) writeFileHeader() error {
+	fh := newFileHeader(db.time.NowUnix(), db.seq)
+
+	_, err := db.file.Write(fh.encode())
+	if err != nil {
+		return err
+	}
+
+	db.kd.advanceOffsetBy(uint64(fh.size()))
+
 	return nil
 }
 
 // Delete deletes a key/value pair if it exists or reports key not found
 // error if the key does not exist
 func (db *DB) Delete(key []byte) error {
-	db.m.Lock()
-	defer db.m.Unlock()
-
-	_, err := db.get(key)
-	if err != nil && !errors.Is(err, ErrCRCFailed) {
-		return err
+	if db.cfg.ReadOnly {
+		return ErrReadOnly
 	}
 
-	h := newKVHeader(db.time.NowUnix(), nil, key)
-
-	err = db.writeKeyVal(h, nil, key)
+	err := checkEntrySize(key, nil)
 	if err != nil {
 		return err
 	}
 
-	db.kd.unset(key)
-
-	return nil
+	return db.commit(&commit{
+		key:    key,
+		delete: true,
+	})
 }
 
 func (db *DB) writeKeyVal(h header, key, val []byte) error {
-	entry := serializeEntry(h, key, val)
+	return db.writeEntries(serializeEntry(h, key, val))
+}
 
-	n, err := db.file.Write(entry)
+// writeEntries appends serialized entries to the active data file (and syncs it if SyncAlways policy is used)
+func (db *DB) writeEntries(entries []byte) error {
+	n, err := db.file.Write(entries)
 	if err != nil {
 		if n > 0 {
-			db.kd.advanceOffsetBy(uint32(n))
+			db.kd.advanceOffsetBy(uint64(n))
 
 			return ErrPartialWrite
 		}
+
+		return err
 	}
 
-	return err
+	if db.cfg.SyncPolicy.mode == syncAlways {
+		err = db.file.Sync()
+		if err != nil {
+			// The entry was written so the following entries should be positioned after it
+			db.kd.advanceOffsetBy(uint64(n))
+
+			return err
+		}
+	}
+
+	return nil
 }
 
 func serializeEntry(h header, key, val []byte) []byte {
-	b := make([]byte, 0, int(headerSize)+len(key)+len(val))
+	b := make([]byte, 0, int(h.size())+len(key)+len(val))
 
 	b = append(b, h.encode()...)
 
@@ -283,32 +706,76 @@ func serializeEntry(h header, key, val []byte) []byte {
 	return b
 }
 
-// Get retrieves a value stored under given key
+// Get retrieves a value stored under given key.
+// The value is served from (and kept in) the value cache if it's enabled
 func (db *DB) Get(key []byte) ([]byte, error) {
+	val, _, err := db.GetWithVersion(key)
+
+	return val, err
+}
+
+// GetWithVersion retrieves a value stored under given key along with its version (sequence number of the write),
+// which can be passed to PutIfVersion in order to update the key only if it was not written in the meantime
+func (db *DB) GetWithVersion(key []byte) ([]byte, uint64, error) {
 	db.m.RLock()
 	defer db.m.RUnlock()
 
-	return db.get(key)
+	ke, err := db.lookup(key)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if val, ok := db.cache.get(key, ke); ok {
+		return val, ke.Seq, nil
+	}
+
+	val, err := db.readValue(key, ke)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	db.cache.set(key, ke, val)
+
+	return val, ke.Seq, nil
 }
 
-func (db *DB) get(key []byte) ([]byte, error) {
+// lookup returns the keydir entry of a key which has not expired
+func (db *DB) lookup(key []byte) (kdEntry, error) {
 	if len(key) == 0 {
-		return nil, ErrInvalidKey
+		return kdEntry{}, ErrInvalidKey
 	}
 
 	ke, err := db.kd.get(key)
 	if err != nil {
-		return nil, err
+		return kdEntry{}, err
+	}
+
+	if ke.isExpired(db.time.NowUnix()) {
+		return kdEntry{}, ErrKeyNotFound
 	}
 
+	return ke, nil
+}
+
+func (db *DB) readValue(key []byte, ke kdEntry) ([]byte, error) {
 	val := make([]byte, ke.ValueSize)
 
-	_, err = db.fs.ReadFileAt(db.path, ke.File, val, int64(ke.ValuePos))
+	_, err := db.fs.ReadFileAt(db.path, ke.File, val, int64(ke.ValuePos))
 	if err != nil {
 		return nil, err
 	}
 
-	if ke.CRC != crc.CalcCRC32(val) {
+	// Header fields and key were checked upon startup, the header is recreated from keydir
+	// so that only the value has to be read
+	h := header{
+		Timestamp: ke.Timestamp,
+		KeySize:   uint32(len(key)),
+		ValueSize: ke.ValueSize,
+		Expiry:    ke.Expiry,
+		Seq:       ke.Seq,
+	}
+
+	if ke.CRC != crc.UpdateCRC32(db.keySum(ke.File, h, key), val) {
 		return nil, ErrCRCFailed
 	}
 
@@ -316,9 +783,10 @@ func (db *DB) get(key []byte) ([]byte, error) {
 }
 
 // Keys returns all keys
+// Since all keys are copied, Iterator or Fold should be preferred for large databases
 func (db *DB) Keys() []string {
 	db.m.RLock()
 	defer db.m.RUnlock()
 
-	return db.kd.keys()
+	return db.kd.keys(db.time.NowUnix())
 }
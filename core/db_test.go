@@ -1,12 +1,15 @@
 package core_test
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"github.com/aneshas/gocask/core"
 	"github.com/aneshas/gocask/core/testutil"
+	"github.com/aneshas/gocask/internal/crc"
 	caskfs "github.com/aneshas/gocask/internal/fs"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"os"
 	gopath "path"
 	"sort"
@@ -53,11 +56,11 @@ func TestShould_Successfully_Store_Values(t *testing.T) {
 			key := []byte(tc.key)
 			val := []byte(tc.val)
 
-			err = db.Put(key, val)
+			_, err = db.Put(key, val)
 
 			assert.NoError(t, err)
 
-			fs.VerifyEntryWritten(t, testutil.Entry(tc.now, key, val))
+			fs.VerifyEntryWritten(t, testutil.Entry(tc.now, 1, key, val))
 
 			assert.NoError(t, db.Close())
 		})
@@ -121,7 +124,7 @@ func saveAndFetch(t *testing.T, dbPath string, fs core.FS) {
 
 			db, _ := core.NewDB(dbPath, fs, testutil.Time(tc.now), config)
 
-			err := db.Put(key, val)
+			_, err := db.Put(key, val)
 
 			assert.NoError(t, err)
 
@@ -257,7 +260,7 @@ func TestShould_Fetch_Existing_Values_After_Startup(t *testing.T) {
 	for _, tc := range seed {
 		fs.AddMockDataFileEntry(
 			tc.file,
-			testutil.Entry(tc.now, []byte(tc.key), []byte(tc.val)),
+			testutil.LegacyEntry(tc.now, []byte(tc.key), []byte(tc.val)),
 		)
 	}
 
@@ -330,7 +333,7 @@ func TestShould_Fetch_Updated_Values_From_Different_Files(t *testing.T) {
 	for _, tc := range seed {
 		fs.AddMockDataFileEntry(
 			tc.file,
-			testutil.Entry(tc.now, []byte(tc.key), []byte(tc.val)),
+			testutil.LegacyEntry(tc.now, []byte(tc.key), []byte(tc.val)),
 		)
 	}
 
@@ -361,7 +364,7 @@ func TestShould_Not_Be_Able_To_Retrieve_Deleted_Key(t *testing.T) {
 	key := []byte("foo")
 	val := []byte("bar")
 
-	_ = db.Put(key, val)
+	_, _ = db.Put(key, val)
 
 	err := db.Delete(key)
 
@@ -382,7 +385,7 @@ func TestShould_Not_Be_Able_To_Retrieve_Deleted_Key_After_Startup(t *testing.T)
 	key := []byte("foo")
 	val := []byte("bar")
 
-	_ = db.Put(key, val)
+	_, _ = db.Put(key, val)
 	_ = db.Delete(key)
 
 	db, _ = core.NewDB("", fs, time, core.DefaultConfig)
@@ -403,9 +406,9 @@ func TestShould_Be_Able_To_Reset_Deleted_Key(t *testing.T) {
 	val := []byte("bar")
 	newVal := []byte("baz")
 
-	_ = db.Put(key, val)
+	_, _ = db.Put(key, val)
 	_ = db.Delete(key)
-	_ = db.Put(key, newVal)
+	_, _ = db.Put(key, newVal)
 
 	got, err := db.Get(key)
 
@@ -453,7 +456,7 @@ func TestShould_Fetch_All_Keys_In_Order(t *testing.T) {
 	for _, tc := range seed {
 		fs.AddMockDataFileEntry(
 			tc.file,
-			testutil.Entry(123, []byte(tc.key), []byte("val")),
+			testutil.LegacyEntry(123, []byte(tc.key), []byte("val")),
 		)
 	}
 
@@ -491,6 +494,116 @@ func TestShould_Not_Fetch_Removed_Keys(t *testing.T) {
 	assert.Equal(t, wantKeys, gotKeys)
 }
 
+func TestShould_Find_Keys_Interleaved_With_Removed_Ones(t *testing.T) {
+	db := getInMemDB(t)
+
+	var wantKeys []string
+
+	for i := 0; i < 10000; i++ {
+		key := []byte(fmt.Sprintf("key_%d", i))
+
+		_, err := db.Put(key, key)
+
+		assert.NoError(t, err)
+	}
+
+	for i := 0; i < 10000; i++ {
+		key := []byte(fmt.Sprintf("key_%d", i))
+
+		if i%3 == 0 {
+			assert.NoError(t, db.Delete(key))
+
+			continue
+		}
+
+		wantKeys = append(wantKeys, string(key))
+	}
+
+	for i := 0; i < 10000; i++ {
+		key := []byte(fmt.Sprintf("key_%d", i))
+
+		val, err := db.Get(key)
+
+		if i%3 == 0 {
+			assert.ErrorIs(t, err, core.ErrKeyNotFound)
+
+			continue
+		}
+
+		assert.NoError(t, err)
+		assert.Equal(t, key, val)
+	}
+
+	gotKeys := db.Keys()
+
+	sort.Strings(gotKeys)
+	sort.Strings(wantKeys)
+
+	assert.Equal(t, wantKeys, gotKeys)
+}
+
+func TestShould_Find_Keys_Left_After_Most_Keys_Are_Removed(t *testing.T) {
+	db := getInMemDB(t)
+
+	defer db.Close()
+
+	key := func(i int) []byte {
+		return []byte(fmt.Sprintf("key_%05d", i))
+	}
+
+	for i := 0; i < 10000; i++ {
+		_, err := db.Put(key(i), key(i))
+
+		assert.NoError(t, err)
+	}
+
+	// Most of the keys are removed, so that the space they took is reclaimed
+	for i := 0; i < 10000; i++ {
+		if i%4 != 0 {
+			assert.NoError(t, db.Delete(key(i)))
+		}
+	}
+
+	// Some of the removed keys are written again
+	for i := 1; i < 10000; i += 4 {
+		_, err := db.Put(key(i), []byte("new"))
+
+		assert.NoError(t, err)
+	}
+
+	var wantKeys []string
+
+	for i := 0; i < 10000; i++ {
+		val, err := db.Get(key(i))
+
+		switch i % 4 {
+		case 0:
+			assert.NoError(t, err)
+			assert.Equal(t, key(i), val)
+		case 1:
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("new"), val)
+		default:
+			assert.ErrorIs(t, err, core.ErrKeyNotFound)
+
+			continue
+		}
+
+		wantKeys = append(wantKeys, string(key(i)))
+	}
+
+	var gotKeys []string
+
+	it := db.Scan([]byte("key_"))
+
+	for it.Next() {
+		gotKeys = append(gotKeys, string(it.Key()))
+	}
+
+	assert.NoError(t, it.Close())
+	assert.Equal(t, wantKeys, gotKeys)
+}
+
 func TestShould_Return_Empty_Keys_Slice_For_Empty_DB(t *testing.T) {
 	var time testutil.Time
 
@@ -510,7 +623,7 @@ func TestRotate_Data_Files_When_Threshold_Size_Is_Exceeded(t *testing.T) {
 		MaxDataFileSize: 65546,
 	})
 
-	err := db.Put([]byte("foo"), aValue(7, 'a'))
+	_, err := db.Put([]byte("foo"), aValue(7, 'a'))
 
 	assert.NoError(t, err)
 
@@ -533,14 +646,14 @@ func TestShould_Fetch_Value_From_Rotated_File(t *testing.T) {
 	keyOld := []byte("akey")
 	valOld := aValue(15, 'd')
 
-	err := db.Put(keyOld, valOld)
+	_, err := db.Put(keyOld, valOld)
 
 	assert.NoError(t, err)
 
 	keyNew := []byte("anotherkey")
 	valNew := aValue(10, 'b')
 
-	err = db.Put(keyNew, valNew)
+	_, err = db.Put(keyNew, valNew)
 
 	assert.NoError(t, err)
 
@@ -574,7 +687,7 @@ func TestPut_Should_Report_Failed_Write_Error(t *testing.T) {
 
 	db, _ := core.NewDB(fs.Path, fs, time, core.DefaultConfig)
 
-	err := db.Put([]byte("key"), []byte("val"))
+	_, err := db.Put([]byte("key"), []byte("val"))
 
 	assert.ErrorIs(t, err, err)
 }
@@ -601,7 +714,7 @@ func TestPut_Should_Report_File_Read_Error_For_Existing_Key(t *testing.T) {
 
 	fs.AddMockDataFileEntry(
 		"data",
-		testutil.Entry(123, key, []byte("value")),
+		testutil.LegacyEntry(123, key, []byte("value")),
 	)
 
 	var time testutil.Time
@@ -626,18 +739,18 @@ func TestShould_Tolerate_Partial_Write_On_Put(t *testing.T) {
 
 	db, _ := core.NewDB(path, fs, time, core.DefaultConfig)
 
-	err := db.Put([]byte("user"), []byte("user123456"))
+	_, err := db.Put([]byte("user"), []byte("user123456"))
 
 	assert.NoError(t, err)
 
-	err = db.Put(key, val)
+	_, err = db.Put(key, val)
 
 	assert.ErrorIs(t, err, core.ErrPartialWrite)
 
 	wantKey := []byte("ishould")
 	wantVal := []byte("befine")
 
-	err = db.Put(wantKey, wantVal)
+	_, err = db.Put(wantKey, wantVal)
 
 	assert.NoError(t, err)
 
@@ -661,7 +774,7 @@ func TestShould_Tolerate_Partial_Write_On_Delete(t *testing.T) {
 
 	assert.NoError(t, err)
 
-	err = db.Put(key, val)
+	_, err = db.Put(key, val)
 
 	assert.NoError(t, err)
 
@@ -675,7 +788,7 @@ func TestShould_Tolerate_Partial_Write_On_Delete(t *testing.T) {
 	wantKey := []byte("ishould")
 	wantVal := []byte("befine")
 
-	err = db.Put(wantKey, wantVal)
+	_, err = db.Put(wantKey, wantVal)
 
 	assert.NoError(t, err)
 
@@ -702,7 +815,7 @@ func validateKey(t *testing.T, key []byte) {
 
 	assert.ErrorIs(t, err, core.ErrInvalidKey)
 
-	err = db.Put(key, []byte("foo"))
+	_, err = db.Put(key, []byte("foo"))
 
 	assert.ErrorIs(t, err, core.ErrInvalidKey)
 
@@ -716,7 +829,7 @@ func TestShould_Validate_Nil_Value(t *testing.T) {
 
 	defer db.Close()
 
-	err := db.Put([]byte("foo"), nil)
+	_, err := db.Put([]byte("foo"), nil)
 
 	assert.ErrorIs(t, err, core.ErrInvalidValue)
 }
@@ -728,9 +841,14 @@ func getInMemDB(t *testing.T) *core.DB {
 
 	inMem := caskfs.NewInMemory()
 
-	db, err := core.NewDB(path, inMem, time, core.DefaultConfig)
+	return openDB(t, path, inMem, time, core.DefaultConfig)
+}
 
-	assert.NoError(t, err)
+// openDB opens the database with the given config and fails the test if it can't be opened
+func openDB(t *testing.T, dbPath string, fs core.FS, time core.Time, cfg core.Config) *core.DB {
+	db, err := core.NewDB(dbPath, fs, time, cfg)
+
+	require.NoError(t, err)
 
 	return db
 }
@@ -747,7 +865,7 @@ func TestShould_Fail_CRC_Check(t *testing.T) {
 	key := []byte("foo")
 	val := []byte("uncorrupted")
 
-	err := db.Put(key, val)
+	_, err := db.Put(key, val)
 
 	assert.NoError(t, err)
 
@@ -756,3 +874,560 @@ func TestShould_Fail_CRC_Check(t *testing.T) {
 	assert.ErrorIs(t, err, core.ErrCRCFailed)
 	assert.Nil(t, got)
 }
+
+func TestMerge_Should_Keep_Only_Live_Values(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_merge")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	var time testutil.Time
+
+	disk := caskfs.NewDisk()
+
+	db, err := core.NewDB(dbPath, disk, time, core.Config{
+		MaxDataFileSize: 64,
+	})
+
+	assert.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		_, err := db.Put([]byte("foo"), []byte(fmt.Sprintf("foo value %d", i)))
+
+		assert.NoError(t, err)
+
+		_, err = db.Put([]byte(fmt.Sprintf("key%d", i)), []byte("some value"))
+
+		assert.NoError(t, err)
+	}
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, db.Delete([]byte(fmt.Sprintf("key%d", i))))
+	}
+
+	filesBefore, _ := disk.Files(dbPath)
+
+	err = db.Merge()
+
+	assert.NoError(t, err)
+
+	filesAfter, _ := disk.Files(dbPath)
+
+	assert.Less(t, len(filesAfter), len(filesBefore))
+
+	assertMergedValues := func(db *core.DB) {
+		got, err := db.Get([]byte("foo"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("foo value 9"), got)
+
+		for i := 0; i < 10; i++ {
+			got, err := db.Get([]byte(fmt.Sprintf("key%d", i)))
+
+			if i < 5 {
+				assert.ErrorIs(t, err, core.ErrKeyNotFound)
+
+				continue
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("some value"), got)
+		}
+	}
+
+	assertMergedValues(db)
+
+	assert.NoError(t, db.Close())
+
+	db, err = core.NewDB(dbPath, disk, time, core.Config{
+		MaxDataFileSize: 64,
+	})
+
+	assert.NoError(t, err)
+
+	assertMergedValues(db)
+
+	_, err = db.Put([]byte("foo"), []byte("after merge"))
+
+	assert.NoError(t, err)
+
+	got, err := db.Get([]byte("foo"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("after merge"), got)
+}
+
+func TestMerge_Should_Be_A_NoOp_Without_Immutable_Data_Files(t *testing.T) {
+	db := getInMemDB(t)
+
+	defer db.Close()
+
+	key := []byte("foo")
+	val := []byte("bar")
+
+	_, _ = db.Put(key, val)
+
+	err := db.Merge()
+
+	assert.NoError(t, err)
+
+	got, err := db.Get(key)
+
+	assert.NoError(t, err)
+	assert.Equal(t, val, got)
+}
+
+func TestMerge_Should_Not_Rewrite_Corrupted_Live_Entries(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_merge")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	var time testutil.Time
+
+	disk := caskfs.NewDisk()
+
+	db := openDB(t, dbPath, disk, time, core.Config{MaxDataFileSize: 96})
+
+	defer db.Close()
+
+	for _, key := range []string{"foo", "bar", "baz"} {
+		_, err := db.Put([]byte(key), []byte(key))
+
+		assert.NoError(t, err)
+	}
+
+	files, _ := disk.Files(dbPath)
+
+	corrupt(t, gopath.Join(dbPath, files[0]+".csk"), func(b []byte) {
+		// Value of the first entry
+		b[24+24+3] ^= 0xff
+	})
+
+	_, err = db.Get([]byte("foo"))
+
+	assert.ErrorIs(t, err, core.ErrCRCFailed)
+	assert.ErrorIs(t, db.Merge(), core.ErrCRCFailed)
+
+	_, err = db.Get([]byte("foo"))
+
+	assert.ErrorIs(t, err, core.ErrCRCFailed)
+
+	val, err := db.Get([]byte("bar"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("bar"), val)
+}
+
+func TestMerge_Should_Not_Remove_Data_File_With_Torn_Tail(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_merge")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	var time testutil.Time
+
+	disk := caskfs.NewDisk()
+	cfg := core.Config{MaxDataFileSize: 96}
+
+	db := openDB(t, dbPath, disk, time, cfg)
+
+	for _, key := range []string{"foo", "bar", "baz"} {
+		_, err := db.Put([]byte(key), []byte(key))
+
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, db.Close())
+
+	files, _ := disk.Files(dbPath)
+	file := gopath.Join(dbPath, files[0]+".csk")
+
+	info, err := os.Stat(file)
+
+	assert.NoError(t, err)
+
+	// Torn tail of an immutable data file is skipped upon startup
+	assert.NoError(t, os.Truncate(file, info.Size()-3))
+
+	db = openDB(t, dbPath, disk, time, cfg)
+
+	defer db.Close()
+
+	assert.ErrorIs(t, db.Merge(), core.ErrTornTail)
+
+	after, err := os.Stat(file)
+
+	assert.NoError(t, err)
+	assert.Equal(t, info.Size()-3, after.Size())
+}
+
+func TestShould_Load_KeyDir_From_Hint_Files_After_Merge(t *testing.T) {
+	dbPath := mergedDB(t)
+
+	defer os.RemoveAll(dbPath)
+
+	disk := caskfs.NewDisk()
+
+	files, _ := disk.Files(dbPath)
+
+	// Data files with a valid hint are never scanned so a torn entry at the end would go unnoticed
+	f, err := os.OpenFile(gopath.Join(dbPath, files[0]+".csk"), os.O_APPEND|os.O_WRONLY, 0755)
+
+	assert.NoError(t, err)
+
+	_, _ = f.Write([]byte{1, 2, 3})
+	_ = f.Close()
+
+	assert.FileExists(t, gopath.Join(dbPath, files[0]+".hint"))
+
+	var time testutil.Time
+
+	db, err := core.NewDB(dbPath, disk, time, core.Config{MaxDataFileSize: 64})
+
+	assert.NoError(t, err)
+
+	assertMergedDB(t, db)
+}
+
+func TestShould_Fall_Back_To_Data_File_Scan_For_Corrupted_Hint_File(t *testing.T) {
+	dbPath := mergedDB(t)
+
+	defer os.RemoveAll(dbPath)
+
+	disk := caskfs.NewDisk()
+
+	files, _ := disk.Files(dbPath)
+
+	for _, file := range files {
+		hint := gopath.Join(dbPath, file+".hint")
+
+		b, err := os.ReadFile(hint)
+		if err != nil {
+			continue
+		}
+
+		b[len(b)-1]++
+
+		assert.NoError(t, os.WriteFile(hint, b, 0755))
+	}
+
+	var time testutil.Time
+
+	db, err := core.NewDB(dbPath, disk, time, core.Config{MaxDataFileSize: 64})
+
+	assert.NoError(t, err)
+
+	assertMergedDB(t, db)
+}
+
+func TestShould_Read_Values_Beyond_4GiB_Offset(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_offset")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	// Sparse data file is scanned only if the hint file is not loaded
+	offset := int64(5 << 30)
+
+	writeHintedDataFile(t, dbPath, offset, 1, "foo")
+
+	var time testutil.Time
+
+	db, err := core.NewDB(dbPath, caskfs.NewDisk(), time, core.Config{MaxDataFileSize: 10 << 30})
+
+	assert.NoError(t, err)
+
+	defer db.Close()
+
+	got, err := db.Get([]byte("foo"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("bar"), got)
+}
+
+func TestShould_Load_KeyDir_From_Legacy_Hint_Files(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_hint")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	// Ghost key exists only in the hint file
+	writeHintedDataFile(t, dbPath, 0, 0, "foo", "ghost")
+
+	var time testutil.Time
+
+	db, err := core.NewDB(dbPath, caskfs.NewDisk(), time, core.Config{MaxDataFileSize: 64})
+
+	assert.NoError(t, err)
+
+	defer db.Close()
+
+	for _, key := range []string{"foo", "ghost"} {
+		got, err := db.Get([]byte(key))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("bar"), got)
+	}
+}
+
+// writeHintedDataFile writes foo=bar entry at the given offset of an immutable data file
+// along with a hint file of the given version mapping all keys to the entry value
+func writeHintedDataFile(t *testing.T, dbPath string, offset int64, version uint16, keys ...string) {
+	val := []byte("bar")
+	valCRC := crc.CalcCRC32(val)
+
+	entry := legacyEntry("foo", string(val))
+
+	f, err := os.Create(gopath.Join(dbPath, "data_1_1.csk"))
+
+	assert.NoError(t, err)
+
+	_, err = f.WriteAt(entry, offset)
+
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	// Active data file
+	assert.NoError(t, os.WriteFile(gopath.Join(dbPath, "data_2_1.csk"), nil, 0755))
+
+	var hint []byte
+
+	if version > 0 {
+		hint = append([]byte("GCHINT"), byte(version), 0)
+	}
+
+	record := func(key string, crc uint32, pos uint64) []byte {
+		b := make([]byte, 24)
+
+		binary.LittleEndian.PutUint32(b[0:4], crc)
+		binary.LittleEndian.PutUint32(b[4:8], 12345)
+		binary.LittleEndian.PutUint32(b[8:12], uint32(len(key)))
+		binary.LittleEndian.PutUint32(b[12:16], uint32(len(val)))
+
+		if version == 0 {
+			binary.LittleEndian.PutUint32(b[16:20], uint32(pos))
+			b = b[:20]
+		} else {
+			binary.LittleEndian.PutUint64(b[16:24], pos)
+		}
+
+		return append(b, key...)
+	}
+
+	for _, key := range keys {
+		hint = append(hint, record(key, valCRC, uint64(offset)+16+3)...)
+	}
+
+	hint = append(hint, record("", crc.CalcCRC32(hint), 0)...)
+
+	assert.NoError(t, os.WriteFile(gopath.Join(dbPath, "data_1_1.hint"), hint, 0755))
+}
+
+func TestShould_Reject_Negative_Max_Data_File_Size(t *testing.T) {
+	var time testutil.Time
+
+	_, err := core.NewDB("", caskfs.NewInMemory(), time, core.Config{MaxDataFileSize: -1})
+
+	assert.ErrorIs(t, err, core.ErrInvalidConfig)
+}
+
+func mergedDB(t *testing.T) string {
+	dbPath, err := os.MkdirTemp("", "gocask_hint")
+
+	assert.NoError(t, err)
+
+	var time testutil.Time
+
+	db, err := core.NewDB(dbPath, caskfs.NewDisk(), time, core.Config{MaxDataFileSize: 64})
+
+	assert.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		_, err := db.Put([]byte(fmt.Sprintf("key%d", i)), []byte("old value"))
+
+		assert.NoError(t, err)
+
+		_, err = db.Put([]byte(fmt.Sprintf("key%d", i)), []byte(fmt.Sprintf("value%d", i)))
+
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, db.Merge())
+	assert.NoError(t, db.Close())
+
+	return dbPath
+}
+
+func assertMergedDB(t *testing.T, db *core.DB) {
+	for i := 0; i < 10; i++ {
+		got, err := db.Get([]byte(fmt.Sprintf("key%d", i)))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(fmt.Sprintf("value%d", i)), got)
+	}
+}
+
+func TestShould_Not_Retrieve_Expired_Key(t *testing.T) {
+	clock := &testutil.Clock{Now: 1000}
+
+	fs := caskfs.NewInMemory()
+
+	db, _ := core.NewDB("", fs, clock, core.DefaultConfig)
+
+	key := []byte("session")
+	val := []byte("data")
+
+	_, err := db.PutWithTTL(key, val, 10*gotime.Second)
+
+	assert.NoError(t, err)
+
+	_, _ = db.Put([]byte("foo"), []byte("bar"))
+
+	clock.Advance(9)
+
+	got, err := db.Get(key)
+
+	assert.NoError(t, err)
+	assert.Equal(t, val, got)
+
+	clock.Advance(1)
+
+	_, err = db.Get(key)
+
+	assert.ErrorIs(t, err, core.ErrKeyNotFound)
+	assert.Equal(t, []string{"foo"}, db.Keys())
+
+	db, _ = core.NewDB("", fs, clock, core.DefaultConfig)
+
+	_, err = db.Get(key)
+
+	assert.ErrorIs(t, err, core.ErrKeyNotFound)
+
+	got, err = db.Get([]byte("foo"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("bar"), got)
+}
+
+func TestShould_Validate_TTL(t *testing.T) {
+	db := getInMemDB(t)
+
+	defer db.Close()
+
+	_, err := db.PutWithTTL([]byte("foo"), []byte("bar"), 0)
+
+	assert.ErrorIs(t, err, core.ErrInvalidTTL)
+}
+
+func TestShould_Reject_TTL_Expiring_Past_Expiry_Timestamp(t *testing.T) {
+	const year = 365 * 24 * gotime.Hour
+
+	db := openDB(t, "mydb", caskfs.NewInMemory(), &testutil.Clock{Now: 1700000000}, core.DefaultConfig)
+
+	defer db.Close()
+
+	// TTL in seconds does not fit 32 bits
+	_, err := db.PutWithTTL([]byte("foo"), []byte("bar"), 200*year)
+
+	assert.ErrorIs(t, err, core.ErrInvalidTTL)
+
+	// TTL fits but the expiry does not
+	_, err = db.PutWithTTL([]byte("foo"), []byte("bar"), 100*year)
+
+	assert.ErrorIs(t, err, core.ErrInvalidTTL)
+
+	var b core.Batch
+
+	b.Put([]byte("bar"), []byte("bar"))
+	b.PutWithTTL([]byte("baz"), []byte("baz"), 100*year)
+
+	assert.ErrorIs(t, db.Write(&b), core.ErrInvalidTTL)
+	assert.Empty(t, db.Keys())
+
+	_, err = db.PutWithTTL([]byte("foo"), []byte("bar"), 50*year)
+
+	assert.NoError(t, err)
+
+	val, err := db.Get([]byte("foo"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("bar"), val)
+}
+
+func TestMerge_Should_Drop_Expired_Keys(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_ttl")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	clock := &testutil.Clock{Now: 1000}
+	cfg := core.Config{MaxDataFileSize: 64}
+
+	db, err := core.NewDB(dbPath, caskfs.NewDisk(), clock, cfg)
+
+	assert.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		_, err := db.PutWithTTL([]byte(fmt.Sprintf("exp%d", i)), []byte("value"), gotime.Minute)
+
+		assert.NoError(t, err)
+
+		_, err = db.Put([]byte(fmt.Sprintf("key%d", i)), []byte("value"))
+
+		assert.NoError(t, err)
+	}
+
+	// Fills up the active data file so that all of the above are merged
+	_, err = db.Put([]byte("filler"), aValue(40, 'f'))
+
+	assert.NoError(t, err)
+
+	clock.Advance(60)
+
+	assert.NoError(t, db.Merge())
+	assert.NoError(t, db.Close())
+
+	clock.Now = 1000
+
+	db, err = core.NewDB(dbPath, caskfs.NewDisk(), clock, cfg)
+
+	assert.NoError(t, err)
+
+	keys := db.Keys()
+
+	sort.Strings(keys)
+
+	assert.Equal(t, []string{"filler", "key0", "key1", "key2", "key3", "key4"}, keys)
+}
+
+func TestShould_Release_DB_Lock_On_Close(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_lock")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	var time testutil.Time
+
+	db, err := core.NewDB(dbPath, caskfs.NewDisk(), time, core.Config{})
+
+	assert.NoError(t, err)
+
+	_, err = core.NewDB(dbPath, caskfs.NewDisk(), time, core.Config{})
+
+	assert.ErrorIs(t, err, core.ErrDatabaseLocked)
+
+	assert.NoError(t, db.Close())
+
+	db, err = core.NewDB(dbPath, caskfs.NewDisk(), time, core.Config{})
+
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+}
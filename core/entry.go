@@ -0,0 +1,209 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/aneshas/gocask/internal/crc"
+	"io"
+)
+
+var (
+	// errCorruptedEntry signifies that an entry which is followed by other entries failed the crc check
+	errCorruptedEntry = fmt.Errorf("%w: entry", ErrCRCFailed)
+
+	// errCorruptedValue signifies that an entry written in the older format failed the crc check.
+	// Only the value of such entries is covered by crc
+	errCorruptedValue = fmt.Errorf("%w: value", ErrCRCFailed)
+
+	// errCorruptedBatch signifies that a batch which is followed by other entries failed the crc check
+	errCorruptedBatch = fmt.Errorf("%w: batch", ErrCRCFailed)
+)
+
+// entry is an entry read from a data file. Tombstone key is stored in place of the value
+type entry struct {
+	h        header
+	key, val []byte
+
+	// offset is the position of the entry in the data file
+	offset uint64
+}
+
+// target returns the key which is written (or deleted) by the entry
+func (e entry) target() []byte {
+	if e.h.isTombstone() {
+		return e.val
+	}
+
+	return e.key
+}
+
+func (e entry) valuePos() uint64 {
+	return e.offset + e.h.entrySize() - uint64(e.h.ValueSize)
+}
+
+// entryReader reads the entries of a data file and checks them against their crc.
+// Entries of a batch are read one by one once the batch was checked as a whole.
+// Entry which was read completely but failed the crc check is returned along with the error (see isCorrupted).
+// Entry (or batch) which was not completely written is reported with one of the errors recognised by isTorn,
+// as is the last entry of the data file which failed the crc check
+type entryReader struct {
+	r      *bufio.Reader
+	offset uint64
+
+	// valueCRC is set for the data files written in the older format whose entries carry crc of the value only
+	valueCRC bool
+
+	// values are read only if set, otherwise they're only checked (tombstone keys are read regardless)
+	values bool
+
+	// bounded reader reads entries which are known to be completely written (eg. the entries of a batch),
+	// so crc failure of the last one is not reported as torn
+	bounded bool
+
+	// batch reads the entries of the batch being read. Batches are not nested
+	batch   *entryReader
+	inBatch bool
+}
+
+// newEntryReader reads the entries of a data file which start at the given offset
+func newEntryReader(r *bufio.Reader, offset uint64, valueCRC, values bool) *entryReader {
+	return &entryReader{
+		r:        r,
+		offset:   offset,
+		valueCRC: valueCRC,
+		values:   values,
+	}
+}
+
+// next returns the next entry or io.EOF once all entries were read
+func (er *entryReader) next() (entry, error) {
+	if er.batch != nil {
+		e, err := er.batch.next()
+		if err == nil {
+			return e, nil
+		}
+
+		er.batch = nil
+
+		if !errors.Is(err, io.EOF) {
+			// Entries were checked as a whole, so they can not be corrupted nor torn unless the batch was
+			return entry{}, errCorruptedBatch
+		}
+	}
+
+	h, err := parseHeader(er.r)
+	if err != nil {
+		return entry{}, err
+	}
+
+	if h.Batch {
+		if er.inBatch {
+			return entry{}, errCorruptedBatch
+		}
+
+		return er.readBatch(h)
+	}
+
+	e := entry{
+		h:      h,
+		offset: er.offset,
+	}
+
+	e.key, err = readFull(er.r, h.KeySize)
+	if err != nil {
+		return entry{}, err
+	}
+
+	sum := er.keySum(h, e.key)
+
+	if er.values || h.isTombstone() {
+		e.val, err = readFull(er.r, h.ValueSize)
+		if err != nil {
+			return entry{}, err
+		}
+
+		sum = crc.UpdateCRC32(sum, e.val)
+	} else {
+		sum, err = discardWithCRC(er.r, sum, int(h.ValueSize))
+		if err != nil {
+			return entry{}, err
+		}
+	}
+
+	er.offset += h.entrySize()
+
+	if h.CRC == sum {
+		return e, nil
+	}
+
+	switch {
+	case !er.bounded && atTail(er.r):
+		return e, errCorruptedTail
+	case er.valueCRC:
+		return e, errCorruptedValue
+	}
+
+	return e, errCorruptedEntry
+}
+
+func (er *entryReader) readBatch(h header) (entry, error) {
+	entries, err := readFull(er.r, h.ValueSize)
+	if err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return entry{}, errTornBatch
+		}
+
+		return entry{}, err
+	}
+
+	if h.CRC != crc.UpdateCRC32(er.keySum(h, nil), entries) {
+		if !er.bounded && atTail(er.r) {
+			return entry{}, errTornBatch
+		}
+
+		return entry{}, errCorruptedBatch
+	}
+
+	er.batch = &entryReader{
+		r:        bufio.NewReader(bytes.NewReader(entries)),
+		offset:   er.offset + uint64(h.size()),
+		valueCRC: er.valueCRC,
+		values:   er.values,
+		bounded:  true,
+		inBatch:  true,
+	}
+
+	er.offset += h.entrySize()
+
+	return er.next()
+}
+
+// readFull reads exactly n bytes. Memory is allocated as the bytes are read,
+// since sizes of the torn (or damaged) entries may well exceed the data file
+func readFull(r io.Reader, n uint32) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(r, int64(n)))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) < int(n) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	return b, nil
+}
+
+func (er *entryReader) keySum(h header, key []byte) uint32 {
+	if er.valueCRC {
+		return 0
+	}
+
+	return h.keySum(key)
+}
+
+// isCorrupted reports whether the entry was read completely but failed the crc check
+func isCorrupted(err error) bool {
+	return errors.Is(err, errCorruptedEntry) || errors.Is(err, errCorruptedValue) || errors.Is(err, errCorruptedTail)
+}
@@ -0,0 +1,9 @@
+package core
+
+// QueuedCommits returns the number of writes waiting to be picked up by a group commit
+func QueuedCommits(db *DB) int {
+	db.gc.m.Lock()
+	defer db.gc.m.Unlock()
+
+	return len(db.gc.queue)
+}
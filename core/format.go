@@ -0,0 +1,261 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/aneshas/gocask/internal/crc"
+	"io"
+)
+
+var (
+	// ErrUnsupportedFormat is thrown when opening a data file written in an unknown format version
+	// or with an unknown checksum algorithm (eg. by a newer version of gocask), or whose file header is damaged
+	ErrUnsupportedFormat = errors.New("gocask: unsupported data file format")
+
+	// errEmptyFile signifies that the data file has no content (not even a file header)
+	errEmptyFile = errors.New("gocask: empty data file")
+
+	// errTornFileHeader signifies that the file header was not written completely
+	// in which case the data file holds no entries
+	errTornFileHeader = errors.New("gocask: data file header not fully written")
+
+	// fileMagic is followed by the format version at the start of the data file.
+	// Data files which do not start with it were written before the format was versioned (version 0)
+	fileMagic = []byte("GOCASK")
+
+	fileHeaderSize uint32 = 16
+
+	// seqFileHeaderSize is the size of the file header which carries the last sequence number
+	seqFileHeaderSize = fileHeaderSize + seqSize
+)
+
+const (
+	// formatVersion0 data files consist of entries only
+	formatVersion0 uint16 = iota
+
+	// formatVersion1 data files start with a file header
+	formatVersion1
+
+	// formatVersion2 entries carry crc covering the header fields, key and value (rather than value only)
+	formatVersion2
+
+	// formatVersion3 entries carry a sequence number and the file header carries the last sequence number
+	// assigned before the data file was created
+	formatVersion3
+
+	formatVersion = formatVersion3
+)
+
+// checksumCRC32 signifies that entries are checked with IEEE crc32
+const checksumCRC32 uint32 = 1
+
+// fileHeader is written at the start of every data file
+type fileHeader struct {
+	Version uint16
+
+	// Created is the unix timestamp of data file creation
+	Created uint32
+
+	// Checksum is the algorithm used to check the entries
+	Checksum uint32
+
+	// Seq is the last sequence number assigned before the data file was created, so that sequence numbers
+	// of the entries which were removed by merge are not assigned again
+	Seq uint64
+}
+
+func newFileHeader(t uint32, seq uint64) fileHeader {
+	return fileHeader{
+		Version:  formatVersion,
+		Created:  t,
+		Checksum: checksumCRC32,
+		Seq:      seq,
+	}
+}
+
+func (fh fileHeader) encode() []byte {
+	b := make([]byte, fh.size())
+
+	copy(b, fileMagic)
+
+	byteOrder.PutUint16(b[6:8], fh.Version)
+	byteOrder.PutUint32(b[8:12], fh.Created)
+	byteOrder.PutUint32(b[12:16], fh.Checksum)
+
+	if fh.Version >= formatVersion3 {
+		byteOrder.PutUint64(b[16:24], fh.Seq)
+	}
+
+	return b
+}
+
+// valueCRC reports whether the entries of the data file carry crc of the value only
+func (fh fileHeader) valueCRC() bool {
+	return fh.Version < formatVersion2
+}
+
+// size returns the number of bytes the header takes at the start of the data file
+func (fh fileHeader) size() uint32 {
+	switch {
+	case fh.Version == formatVersion0:
+		return 0
+	case fh.Version >= formatVersion3:
+		return seqFileHeaderSize
+	}
+
+	return fileHeaderSize
+}
+
+// setFormat records the format of the named data file
+// and advances the last sequence number past the one recorded in its file header.
+// Legacy data files precede all versioned ones, so a data file without the file header
+// which follows a versioned one is reported as damaged
+func (db *DB) setFormat(file string, fh fileHeader) error {
+	if fh.Version == formatVersion0 && db.versioned {
+		return fmt.Errorf("%w: missing file header of %v", ErrUnsupportedFormat, file)
+	}
+
+	if fh.Version != formatVersion0 {
+		db.versioned = true
+	}
+
+	if fh.valueCRC() {
+		db.valueCRC[file] = true
+	}
+
+	db.observeSeq(fh.Seq)
+
+	return nil
+}
+
+// readFileHeader reads the file header of the named data file
+func (db *DB) readFileHeader(file string) (fileHeader, error) {
+	return readFileHeader(bufio.NewReader(&fileReader{
+		fs:   db.fs,
+		path: db.path,
+		file: file,
+	}))
+}
+
+// keySum returns crc of the entry before it is updated with the value.
+// Entries of data files written in the older format carry crc of the value only
+func (db *DB) keySum(file string, h header, key []byte) uint32 {
+	if db.valueCRC[file] {
+		return 0
+	}
+
+	return h.keySum(key)
+}
+
+// legacyFile reports whether the data file which does not start with the file magic was written
+// before the format was versioned. Legacy data file starts with an entry whose crc covers its value.
+// Otherwise, file header whose magic was damaged is recognised by the format version and checksum algorithm
+// which follow it (these can not be mistaken for the timestamp and value size of a legacy entry)
+func legacyFile(r *bufio.Reader, b []byte) bool {
+	var header bool
+
+	if len(b) >= int(fileHeaderSize) {
+		version := byteOrder.Uint16(b[6:8])
+
+		header = version != formatVersion0 && version <= formatVersion && byteOrder.Uint32(b[12:16]) == checksumCRC32
+	}
+
+	return !header || firstEntryChecks(r)
+}
+
+// firstEntryChecks reports whether the data file starts with a legacy entry which passes the crc check
+func firstEntryChecks(r *bufio.Reader) bool {
+	b, err := r.Peek(int(headerSize))
+	if err != nil {
+		return false
+	}
+
+	keySize := byteOrder.Uint32(b[8:12])
+
+	if keySize&flagsMask != 0 {
+		return false
+	}
+
+	n := uint64(headerSize) + uint64(keySize) + uint64(byteOrder.Uint32(b[12:16]))
+
+	if n > uint64(r.Size()) {
+		return false
+	}
+
+	b, err = r.Peek(int(n))
+	if err != nil {
+		return false
+	}
+
+	return byteOrder.Uint32(b[0:4]) == crc.CalcCRC32(b[uint64(headerSize)+uint64(keySize):])
+}
+
+// readFileHeader consumes the file header if there is one. Legacy data file is reported as version 0
+func readFileHeader(r *bufio.Reader) (fileHeader, error) {
+	b, err := r.Peek(int(fileHeaderSize))
+	if len(b) == 0 {
+		if errors.Is(err, io.EOF) {
+			return fileHeader{}, errEmptyFile
+		}
+
+		return fileHeader{}, err
+	}
+
+	n := len(fileMagic)
+
+	if len(b) < n {
+		n = len(b)
+	}
+
+	if !bytes.Equal(b[:n], fileMagic[:n]) {
+		if !legacyFile(r, b) {
+			return fileHeader{}, fmt.Errorf("%w: damaged file header", ErrUnsupportedFormat)
+		}
+
+		return fileHeader{Version: formatVersion0}, nil
+	}
+
+	if len(b) < int(fileHeaderSize) {
+		if errors.Is(err, io.EOF) {
+			return fileHeader{}, errTornFileHeader
+		}
+
+		return fileHeader{}, err
+	}
+
+	fh := fileHeader{
+		Version:  byteOrder.Uint16(b[6:8]),
+		Created:  byteOrder.Uint32(b[8:12]),
+		Checksum: byteOrder.Uint32(b[12:16]),
+	}
+
+	if fh.Version == formatVersion0 || fh.Version > formatVersion {
+		return fileHeader{}, fmt.Errorf("%w: version %d", ErrUnsupportedFormat, fh.Version)
+	}
+
+	if fh.Checksum != checksumCRC32 {
+		return fileHeader{}, fmt.Errorf("%w: checksum algorithm %d", ErrUnsupportedFormat, fh.Checksum)
+	}
+
+	if fh.Version >= formatVersion3 {
+		b, err = r.Peek(int(seqFileHeaderSize))
+		if len(b) < int(seqFileHeaderSize) {
+			if errors.Is(err, io.EOF) {
+				return fileHeader{}, errTornFileHeader
+			}
+
+			return fileHeader{}, err
+		}
+
+		fh.Seq = byteOrder.Uint64(b[16:24])
+	}
+
+	_, err = r.Discard(int(fh.size()))
+	if err != nil {
+		return fileHeader{}, err
+	}
+
+	return fh, nil
+}
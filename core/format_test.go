@@ -0,0 +1,478 @@
+package core_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/aneshas/gocask/core"
+	"github.com/aneshas/gocask/core/testutil"
+	"github.com/aneshas/gocask/internal/crc"
+	caskfs "github.com/aneshas/gocask/internal/fs"
+	"github.com/stretchr/testify/assert"
+	"os"
+	gopath "path"
+	"testing"
+)
+
+func TestShould_Read_And_Migrate_Legacy_Data_Files(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_format")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	writeFile(t, dbPath, "data_1_1.csk", legacyEntry("foo", "old"), legacyEntry("bar", "bar"))
+	writeFile(t, dbPath, "data_2_1.csk", legacyEntry("foo", "new"))
+
+	var time testutil.Time
+
+	disk := caskfs.NewDisk()
+
+	db, err := core.NewDB(dbPath, disk, time, core.Config{MaxDataFileSize: 1024})
+
+	assert.NoError(t, err)
+
+	assertValues := func() {
+		for key, val := range map[string]string{"foo": "new", "bar": "bar", "baz": "baz"} {
+			got, err := db.Get([]byte(key))
+
+			assert.NoError(t, err)
+			assert.Equal(t, []byte(val), got)
+		}
+	}
+
+	_, err = db.Put([]byte("baz"), []byte("baz"))
+
+	assert.NoError(t, err)
+
+	assertValues()
+
+	// Legacy active data file is rotated so new writes go to a data file of the current format
+	files, _ := disk.Files(dbPath)
+
+	assert.Len(t, files, 3)
+
+	assert.NoError(t, db.Merge())
+
+	assertValues()
+
+	assert.NoError(t, db.Close())
+
+	files, _ = disk.Files(dbPath)
+
+	for _, file := range files {
+		b, err := os.ReadFile(gopath.Join(dbPath, file+".csk"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("GOCASK"), b[:6])
+	}
+
+	db, err = core.NewDB(dbPath, disk, time, core.Config{MaxDataFileSize: 1024})
+
+	assert.NoError(t, err)
+
+	defer db.Close()
+
+	assertValues()
+}
+
+func TestShould_Reject_Unsupported_Format_Version(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_format")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	header := make([]byte, 16)
+
+	copy(header, "GOCASK")
+	binary.LittleEndian.PutUint16(header[6:8], 99)
+	binary.LittleEndian.PutUint32(header[12:16], 1)
+
+	writeFile(t, dbPath, "data_1_1.csk", header)
+
+	var time testutil.Time
+
+	_, err = core.NewDB(dbPath, caskfs.NewDisk(), time, core.Config{MaxDataFileSize: 1024})
+
+	assert.ErrorIs(t, err, core.ErrUnsupportedFormat)
+}
+
+func TestShould_Truncate_Active_Data_File_With_Torn_File_Header(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_format")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	writeFile(t, dbPath, "data_1_1.csk", legacyEntry("foo", "bar"))
+	writeFile(t, dbPath, "data_2_1.csk", []byte("GOCA"))
+
+	var time testutil.Time
+
+	disk := caskfs.NewDisk()
+
+	db, err := core.NewDB(dbPath, disk, time, core.Config{MaxDataFileSize: 1024})
+
+	assert.NoError(t, err)
+
+	_, err = db.Put([]byte("baz"), []byte("baz"))
+
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+
+	files, _ := disk.Files(dbPath)
+
+	assert.Len(t, files, 2)
+
+	db, err = core.NewDB(dbPath, disk, time, core.Config{MaxDataFileSize: 1024})
+
+	assert.NoError(t, err)
+
+	defer db.Close()
+
+	for key, val := range map[string]string{"foo": "bar", "baz": "baz"} {
+		got, err := db.Get([]byte(key))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(val), got)
+	}
+}
+
+func TestShould_Read_Entries_Checked_By_Value_Only_From_Older_Format(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_format")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	writeFile(t, dbPath, "data_1_1.csk", fileHeaderV1(), legacyEntry("foo", "foo"), legacyEntry("bar", "bar"))
+
+	var time testutil.Time
+
+	disk := caskfs.NewDisk()
+
+	db, err := core.NewDB(dbPath, disk, time, core.Config{MaxDataFileSize: 1024})
+
+	assert.NoError(t, err)
+
+	assertValues := func() {
+		for _, key := range []string{"foo", "bar"} {
+			got, err := db.Get([]byte(key))
+
+			assert.NoError(t, err)
+			assert.Equal(t, []byte(key), got)
+		}
+	}
+
+	assertValues()
+
+	assert.NoError(t, db.Merge())
+
+	assertValues()
+
+	assert.NoError(t, db.Close())
+
+	db, err = core.NewDB(dbPath, disk, time, core.Config{MaxDataFileSize: 1024})
+
+	assert.NoError(t, err)
+
+	defer db.Close()
+
+	assertValues()
+}
+
+func TestShould_Fail_Startup_On_Corrupted_Key(t *testing.T) {
+	dbPath, file := writeEntries(t, "foo", "bar")
+
+	defer os.RemoveAll(dbPath)
+
+	corrupt(t, file, func(b []byte) {
+		// Key of the first entry follows the file header and entry header
+		b[24+24] ^= 0xff
+	})
+
+	var time testutil.Time
+
+	_, err := core.NewDB(dbPath, caskfs.NewDisk(), time, core.Config{MaxDataFileSize: 1024})
+
+	assert.ErrorIs(t, err, core.ErrCRCFailed)
+}
+
+func TestShould_Fail_Startup_On_Corrupted_Entry_Header(t *testing.T) {
+	dbPath, file := writeEntries(t, "foo", "bar")
+
+	defer os.RemoveAll(dbPath)
+
+	corrupt(t, file, func(b []byte) {
+		// Timestamp of the first entry
+		b[24+4] ^= 0xff
+	})
+
+	var time testutil.Time
+
+	_, err := core.NewDB(dbPath, caskfs.NewDisk(), time, core.Config{MaxDataFileSize: 1024})
+
+	assert.ErrorIs(t, err, core.ErrCRCFailed)
+}
+
+func TestShould_Fail_Startup_On_Damaged_File_Magic(t *testing.T) {
+	cases := []struct {
+		name   string
+		damage func(b []byte)
+	}{
+		{
+			name: "single byte",
+			damage: func(b []byte) {
+				// "A" of the file magic
+				b[3] ^= 0xff
+			},
+		},
+		{
+			name: "whole magic",
+			damage: func(b []byte) {
+				for i := range "GOCASK" {
+					b[i] ^= 0xff
+				}
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dbPath, file := writeEntries(t, "foo", "bar")
+
+			defer os.RemoveAll(dbPath)
+
+			corrupt(t, file, tc.damage)
+
+			var time testutil.Time
+
+			_, err := core.NewDB(dbPath, caskfs.NewDisk(), time, core.Config{MaxDataFileSize: 1024})
+
+			assert.ErrorIs(t, err, core.ErrUnsupportedFormat)
+		})
+	}
+}
+
+func TestShould_Read_Legacy_Data_File_Resembling_File_Header(t *testing.T) {
+	// Crc of the value matches the file magic in two bytes, which is followed by "SK" of the timestamp
+	var val string
+
+	for i := 0; ; i++ {
+		val = fmt.Sprintf("val_%d", i)
+
+		var b [4]byte
+
+		binary.LittleEndian.PutUint32(b[:], crc.CalcCRC32([]byte(val)))
+
+		var same int
+
+		for j := range b {
+			if b[j] == "GOCA"[j] {
+				same++
+			}
+		}
+
+		if same >= 2 {
+			break
+		}
+	}
+
+	cases := []struct {
+		name  string
+		entry []byte
+	}{
+		{
+			name:  "magic",
+			entry: legacyEntryAt(0x4b53, "foo", val),
+		},
+		{
+			// Timestamp is followed by the value size, which take the place of the format version and checksum algorithm
+			name:  "format version",
+			entry: legacyEntryAt(uint32(3)<<16, "foo", "x"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dbPath, err := os.MkdirTemp("", "gocask_format")
+
+			assert.NoError(t, err)
+
+			defer os.RemoveAll(dbPath)
+
+			writeFile(t, dbPath, "data_1_1.csk", tc.entry, legacyEntry("bar", "bar"))
+
+			var time testutil.Time
+
+			db, err := core.NewDB(dbPath, caskfs.NewDisk(), time, core.Config{MaxDataFileSize: 1024})
+
+			assert.NoError(t, err)
+
+			defer db.Close()
+
+			_, err = db.Get([]byte("foo"))
+
+			assert.NoError(t, err)
+
+			val, err := db.Get([]byte("bar"))
+
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("bar"), val)
+		})
+	}
+}
+
+func TestShould_Fail_Startup_On_Missing_File_Header_After_Versioned_Data_File(t *testing.T) {
+	dbPath, file := writeEntries(t, "foo", "bar")
+
+	defer os.RemoveAll(dbPath)
+
+	// Data file which is ordered after the versioned one can't be a legacy one
+	writeFile(t, dbPath, gopath.Base(file[:len(file)-len(".csk")])+"_1.csk", legacyEntry("baz", "baz"))
+
+	var time testutil.Time
+
+	_, err := core.NewDB(dbPath, caskfs.NewDisk(), time, core.Config{MaxDataFileSize: 1024})
+
+	assert.ErrorIs(t, err, core.ErrUnsupportedFormat)
+}
+
+func TestShould_Report_Corrupted_Value_On_Get(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_format")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	var time testutil.Time
+
+	disk := caskfs.NewDisk()
+
+	db, err := core.NewDB(dbPath, disk, time, core.Config{MaxDataFileSize: 1024})
+
+	assert.NoError(t, err)
+
+	defer db.Close()
+
+	_, err = db.Put([]byte("foo"), []byte("foo"))
+
+	assert.NoError(t, err)
+
+	_, err = db.Put([]byte("bar"), []byte("bar"))
+
+	assert.NoError(t, err)
+
+	files, _ := disk.Files(dbPath)
+
+	corrupt(t, gopath.Join(dbPath, files[0]+".csk"), func(b []byte) {
+		// Value of the first entry
+		b[24+24+3] ^= 0xff
+	})
+
+	_, err = db.Get([]byte("foo"))
+
+	assert.ErrorIs(t, err, core.ErrCRCFailed)
+
+	got, err := db.Get([]byte("bar"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("bar"), got)
+}
+
+func writeEntries(t *testing.T, keys ...string) (string, string) {
+	dbPath, err := os.MkdirTemp("", "gocask_format")
+
+	assert.NoError(t, err)
+
+	var time testutil.Time
+
+	disk := caskfs.NewDisk()
+
+	db, err := core.NewDB(dbPath, disk, time, core.Config{MaxDataFileSize: 1024})
+
+	assert.NoError(t, err)
+
+	for _, key := range keys {
+		_, err := db.Put([]byte(key), []byte(key))
+
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, db.Close())
+
+	files, _ := disk.Files(dbPath)
+
+	return dbPath, gopath.Join(dbPath, files[0]+".csk")
+}
+
+// rotateFailingFS fails to rotate the active data file
+type rotateFailingFS struct {
+	core.FS
+}
+
+func (rotateFailingFS) Rotate(string) (core.File, error) {
+	return nil, errors.New("rotate failed")
+}
+
+func TestShould_Report_Failed_Rotation_Of_Legacy_Active_Data_File_Upon_Startup(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_format")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	writeFile(t, dbPath, "data_1_1.csk", legacyEntry("foo", "foo"))
+
+	var time testutil.Time
+
+	_, err = core.NewDB(dbPath, rotateFailingFS{FS: caskfs.NewDisk()}, time, core.Config{MaxDataFileSize: 1024})
+
+	assert.EqualError(t, err, "rotate failed")
+}
+
+func corrupt(t *testing.T, file string, f func([]byte)) {
+	b, err := os.ReadFile(file)
+
+	assert.NoError(t, err)
+
+	f(b)
+
+	assert.NoError(t, os.WriteFile(file, b, 0755))
+}
+
+func fileHeaderV1() []byte {
+	b := make([]byte, 16)
+
+	copy(b, "GOCASK")
+	binary.LittleEndian.PutUint16(b[6:8], 1)
+	binary.LittleEndian.PutUint32(b[12:16], 1)
+
+	return b
+}
+
+func legacyEntry(key, val string) []byte {
+	return legacyEntryAt(12345, key, val)
+}
+
+func legacyEntryAt(timestamp uint32, key, val string) []byte {
+	b := make([]byte, 16)
+
+	binary.LittleEndian.PutUint32(b[0:4], crc.CalcCRC32([]byte(val)))
+	binary.LittleEndian.PutUint32(b[4:8], timestamp)
+	binary.LittleEndian.PutUint32(b[8:12], uint32(len(key)))
+	binary.LittleEndian.PutUint32(b[12:16], uint32(len(val)))
+
+	return append(b, key+val...)
+}
+
+func writeFile(t *testing.T, dbPath, name string, chunks ...[]byte) {
+	var b []byte
+
+	for _, c := range chunks {
+		b = append(b, c...)
+	}
+
+	assert.NoError(t, os.WriteFile(gopath.Join(dbPath, name), b, 0755))
+}
@@ -4,18 +4,48 @@ import (
 	"encoding/binary"
 	"github.com/aneshas/gocask/internal/crc"
 	"io"
+	"math"
 )
 
 var (
 	byteOrder  binary.ByteOrder = binary.LittleEndian
 	headerSize uint32           = 16
+
+	// expiryFlag is set on the key size of headers which are followed by an expiry timestamp
+	expiryFlag uint32 = 1 << 31
+	expirySize uint32 = 4
+
+	// batchFlag is set on the key size of headers which frame a batch of entries
+	batchFlag uint32 = 1 << 30
+
+	// seqFlag is set on the key size of headers which are followed by a sequence number (after the expiry if any)
+	seqFlag uint32 = 1 << 29
+	seqSize uint32 = 8
+
+	flagsMask = expiryFlag | batchFlag | seqFlag
+
+	// maxKeySize is the largest key size which does not overlap with the flags
+	maxKeySize uint64 = 1<<29 - 1
+
+	// maxValueSize is the largest value (or batch) size which fits the header
+	maxValueSize uint64 = math.MaxUint32
 )
 
 type header struct {
 	CRC, Timestamp, KeySize, ValueSize uint32
+
+	// Expiry is an optional unix timestamp after which the entry is considered deleted
+	Expiry uint32
+
+	// Batch marks a header which is followed by a group of serialized entries (in place of value)
+	// that should be applied atomically
+	Batch bool
+
+	// Seq is the sequence number of the entry. Entries written in the older formats carry none (zero)
+	Seq uint64
 }
 
-func newKVHeader(t uint32, key, val []byte) header {
+func newKVHeader(t, expiry uint32, seq uint64, key, val []byte) header {
 	var kSize uint32
 
 	if key != nil {
@@ -24,7 +54,22 @@ func newKVHeader(t uint32, key, val []byte) header {
 
 	vSize := uint32(len(val))
 
-	return newHeader(crc.CalcCRC32(val), t, kSize, vSize)
+	h := newHeader(0, t, kSize, vSize)
+
+	h.Expiry = expiry
+	h.Seq = seq
+	h.CRC = h.checksum(key, val)
+
+	return h
+}
+
+func newBatchHeader(t uint32, entries []byte) header {
+	h := newHeader(0, t, 0, uint32(len(entries)))
+
+	h.Batch = true
+	h.CRC = h.checksum(nil, entries)
+
+	return h
 }
 
 func newHeader(crc, t, ksz, vsz uint32) header {
@@ -37,26 +82,119 @@ func newHeader(crc, t, ksz, vsz uint32) header {
 }
 
 func (h header) encode() []byte {
-	b := make([]byte, headerSize)
+	b := make([]byte, h.size())
+
+	keySize := h.KeySize
+	offset := headerSize
+
+	if h.Expiry != 0 {
+		keySize |= expiryFlag
+
+		byteOrder.PutUint32(b[offset:], h.Expiry)
+
+		offset += expirySize
+	}
+
+	if h.Seq != 0 {
+		keySize |= seqFlag
+
+		byteOrder.PutUint64(b[offset:], h.Seq)
+	}
+
+	if h.Batch {
+		keySize |= batchFlag
+	}
 
 	byteOrder.PutUint32(b[0:4], h.CRC)
 	byteOrder.PutUint32(b[4:8], h.Timestamp)
-	byteOrder.PutUint32(b[8:12], h.KeySize)
-	byteOrder.PutUint32(b[12:], h.ValueSize)
+	byteOrder.PutUint32(b[8:12], keySize)
+	byteOrder.PutUint32(b[12:16], h.ValueSize)
 
 	return b
 }
 
-func (h header) entrySize() uint32 {
-	return headerSize + h.KeySize + h.ValueSize
+// checksum calculates crc covering the header fields (all but the crc itself), key and value
+func (h header) checksum(key, val []byte) uint32 {
+	return crc.UpdateCRC32(h.keySum(key), val)
+}
+
+// keySum calculates crc of the header fields and key, which is then updated with the value
+func (h header) keySum(key []byte) uint32 {
+	sum := crc.UpdateCRC32(0, h.encode()[4:])
+
+	return crc.UpdateCRC32(sum, key)
+}
+
+func (h header) size() uint32 {
+	size := headerSize
+
+	if h.Expiry != 0 {
+		size += expirySize
+	}
+
+	if h.Seq != 0 {
+		size += seqSize
+	}
+
+	return size
+}
+
+func (h header) entrySize() uint64 {
+	return uint64(h.size()) + uint64(h.KeySize) + uint64(h.ValueSize)
+}
+
+// checkEntrySize reports ErrEntryTooLarge if the key or value can not be addressed by the header
+func checkEntrySize(key, val []byte) error {
+	if uint64(len(key)) > maxKeySize || uint64(len(val)) > maxValueSize {
+		return ErrEntryTooLarge
+	}
+
+	return nil
 }
 
 func (h header) isTombstone() bool {
-	return h.KeySize == 0
+	return h.KeySize == 0 && !h.Batch
 }
 
 func parseHeader(r io.Reader) (header, error) {
-	h := header{}
+	b := make([]byte, headerSize)
+
+	_, err := io.ReadFull(r, b)
+	if err != nil {
+		return header{}, err
+	}
+
+	keySize := byteOrder.Uint32(b[8:12])
+
+	h := header{
+		CRC:       byteOrder.Uint32(b[0:4]),
+		Timestamp: byteOrder.Uint32(b[4:8]),
+		KeySize:   keySize &^ flagsMask,
+		ValueSize: byteOrder.Uint32(b[12:16]),
+		Batch:     keySize&batchFlag != 0,
+	}
+
+	if keySize&expiryFlag != 0 {
+		err = binary.Read(r, byteOrder, &h.Expiry)
+		if err != nil {
+			return header{}, eofToUnexpected(err)
+		}
+	}
+
+	if keySize&seqFlag != 0 {
+		err = binary.Read(r, byteOrder, &h.Seq)
+		if err != nil {
+			return header{}, eofToUnexpected(err)
+		}
+	}
+
+	return h, nil
+}
+
+func eofToUnexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
 
-	return h, binary.Read(r, byteOrder, &h)
+	return err
 }
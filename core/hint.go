@@ -0,0 +1,271 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"github.com/aneshas/gocask/internal/crc"
+	"io"
+)
+
+var (
+	// ErrInvalidHint signifies that the hint file is incomplete or corrupted
+	// in which case keydir is rebuilt from the data file itself
+	ErrInvalidHint = errors.New("gocask: hint file is corrupted")
+
+	// hintMagic is followed by the format version at the start of the hint file.
+	// Hint files which do not start with it were written before the format was versioned (version 0)
+	hintMagic = []byte("GCHINT")
+
+	hintPrologueSize = len(hintMagic) + 2
+)
+
+const (
+	// hintVersion0 stores 32-bit value positions
+	hintVersion0 uint16 = iota
+
+	// hintVersion1 stores 64-bit value positions
+	hintVersion1
+
+	// hintVersion2 stores sequence numbers
+	hintVersion2
+
+	hintVersion = hintVersion2
+)
+
+// hintHeader represents a single hint file record header which is followed by the key.
+// A hint file is terminated with a record with zero key size carrying the crc of the whole hint file
+type hintHeader struct {
+	CRC, Timestamp, KeySize, ValueSize uint32
+	ValuePos                           uint64
+	Expiry                             uint32
+	Seq                                uint64
+}
+
+func hintHeaderSize(version uint16) int {
+	switch version {
+	case hintVersion0:
+		return 20
+	case hintVersion1:
+		return 24
+	}
+
+	return 32
+}
+
+func hintPrologue() []byte {
+	b := make([]byte, hintPrologueSize)
+
+	copy(b, hintMagic)
+
+	byteOrder.PutUint16(b[len(hintMagic):], hintVersion)
+
+	return b
+}
+
+func (h hintHeader) encode() []byte {
+	size := hintHeaderSize(hintVersion)
+	keySize := h.KeySize
+
+	if h.Expiry != 0 {
+		size += int(expirySize)
+		keySize |= expiryFlag
+	}
+
+	b := make([]byte, size)
+
+	byteOrder.PutUint32(b[0:4], h.CRC)
+	byteOrder.PutUint32(b[4:8], h.Timestamp)
+	byteOrder.PutUint32(b[8:12], keySize)
+	byteOrder.PutUint32(b[12:16], h.ValueSize)
+	byteOrder.PutUint64(b[16:24], h.ValuePos)
+	byteOrder.PutUint64(b[24:32], h.Seq)
+
+	if h.Expiry != 0 {
+		byteOrder.PutUint32(b[32:], h.Expiry)
+	}
+
+	return b
+}
+
+func decodeHintHeader(b []byte, version uint16) hintHeader {
+	h := hintHeader{
+		CRC:       byteOrder.Uint32(b[0:4]),
+		Timestamp: byteOrder.Uint32(b[4:8]),
+		KeySize:   byteOrder.Uint32(b[8:12]),
+		ValueSize: byteOrder.Uint32(b[12:16]),
+	}
+
+	if version == hintVersion0 {
+		h.ValuePos = uint64(byteOrder.Uint32(b[16:20]))
+	} else {
+		h.ValuePos = byteOrder.Uint64(b[16:24])
+	}
+
+	if version >= hintVersion2 {
+		h.Seq = byteOrder.Uint64(b[24:32])
+	}
+
+	return h
+}
+
+type hintWriter struct {
+	file File
+	crc  uint32
+}
+
+func newHintWriter(file File) (*hintWriter, error) {
+	w := hintWriter{file: file}
+
+	b := hintPrologue()
+
+	w.crc = crc.UpdateCRC32(w.crc, b)
+
+	_, err := file.Write(b)
+	if err != nil {
+		_ = file.Close()
+
+		return nil, err
+	}
+
+	return &w, nil
+}
+
+func (w *hintWriter) write(ke kdEntry, key []byte) error {
+	h := hintHeader{
+		CRC:       ke.CRC,
+		Timestamp: ke.Timestamp,
+		KeySize:   uint32(len(key)),
+		ValueSize: ke.ValueSize,
+		ValuePos:  ke.ValuePos,
+		Expiry:    ke.Expiry,
+		Seq:       ke.Seq,
+	}
+
+	b := append(h.encode(), key...)
+
+	w.crc = crc.UpdateCRC32(w.crc, b)
+
+	_, err := w.file.Write(b)
+
+	return err
+}
+
+func (w *hintWriter) close() error {
+	_, err := w.file.Write(hintHeader{CRC: w.crc}.encode())
+	if err == nil {
+		err = w.file.Sync()
+	}
+
+	if err != nil {
+		_ = w.file.Close()
+
+		return err
+	}
+
+	return w.file.Close()
+}
+
+type hint struct {
+	key   []byte
+	entry kdEntry
+}
+
+func readHints(file File, dataFile string) ([]hint, error) {
+	var (
+		r     = bufio.NewReader(file)
+		sum   uint32
+		hints []hint
+	)
+
+	version, prologue, err := readHintPrologue(r)
+	if err != nil {
+		return nil, err
+	}
+
+	sum = crc.UpdateCRC32(sum, prologue)
+
+	for {
+		hb := make([]byte, hintHeaderSize(version))
+
+		_, err := io.ReadFull(r, hb)
+		if err != nil {
+			return nil, ErrInvalidHint
+		}
+
+		h := decodeHintHeader(hb, version)
+
+		if h.KeySize&expiryFlag != 0 {
+			eb := make([]byte, expirySize)
+
+			_, err = io.ReadFull(r, eb)
+			if err != nil {
+				return nil, ErrInvalidHint
+			}
+
+			hb = append(hb, eb...)
+
+			h.KeySize &^= flagsMask
+			h.Expiry = byteOrder.Uint32(eb)
+		}
+
+		if h.KeySize == 0 {
+			if h.CRC != sum {
+				return nil, ErrInvalidHint
+			}
+
+			break
+		}
+
+		key := make([]byte, h.KeySize)
+
+		_, err = io.ReadFull(r, key)
+		if err != nil {
+			return nil, ErrInvalidHint
+		}
+
+		sum = crc.UpdateCRC32(sum, hb)
+		sum = crc.UpdateCRC32(sum, key)
+
+		hints = append(hints, hint{
+			key: key,
+			entry: kdEntry{
+				CRC:       h.CRC,
+				Timestamp: h.Timestamp,
+				ValuePos:  h.ValuePos,
+				ValueSize: h.ValueSize,
+				Expiry:    h.Expiry,
+				Seq:       h.Seq,
+				File:      dataFile,
+			},
+		})
+	}
+
+	_, err = r.ReadByte()
+	if !errors.Is(err, io.EOF) {
+		return nil, ErrInvalidHint
+	}
+
+	return hints, nil
+}
+
+// readHintPrologue consumes the hint file prologue if there is one and returns the format version.
+// Legacy hint file which happens to start with the magic fails the crc check
+// and is ignored, so misdetection merely costs a data file scan
+func readHintPrologue(r *bufio.Reader) (uint16, []byte, error) {
+	b, err := r.Peek(hintPrologueSize)
+	if err != nil || !bytes.Equal(b[:len(hintMagic)], hintMagic) {
+		return hintVersion0, nil, nil
+	}
+
+	version := byteOrder.Uint16(b[len(hintMagic):])
+	if version == hintVersion0 || version > hintVersion {
+		return 0, nil, ErrInvalidHint
+	}
+
+	prologue := make([]byte, hintPrologueSize)
+
+	_, _ = io.ReadFull(r, prologue)
+
+	return version, prologue, nil
+}
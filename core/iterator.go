@@ -0,0 +1,223 @@
+package core
+
+// IteratorOption configures Iterator and Fold
+type IteratorOption func(*iteratorOptions)
+
+type iteratorOptions struct {
+	sorted bool
+	start  string
+	end    *string
+}
+
+// Sorted iterates over keys in lexicographic order
+func Sorted() IteratorOption {
+	return func(o *iteratorOptions) {
+		o.sorted = true
+	}
+}
+
+// Iterator iterates over key/value pairs of the database. Unless Sorted option is used,
+// keys are visited in no particular order. Neither keys nor values are copied up front.
+// Db lock is not held between the calls to Next so the database can be modified during iteration,
+// in which case keys written after the iterator was created may or may not be visited
+type Iterator struct {
+	db     *DB
+	snap   *Snapshot
+	opts   iteratorOptions
+	pos    kdPos
+	cursor *kdCursor
+	key    []byte
+	entry  kdEntry
+	err    error
+	closed bool
+}
+
+// Iterator creates a new iterator which is positioned before the first key
+func (db *DB) Iterator(opts ...IteratorOption) *Iterator {
+	return db.newIterator(nil, opts...)
+}
+
+// Scan creates a new sorted iterator over keys starting with the given prefix
+func (db *DB) Scan(prefix []byte) *Iterator {
+	return db.Range(prefix, prefixEnd(prefix))
+}
+
+// Range creates a new sorted iterator over keys in range [start, end).
+// Nil start or end leave the range unbounded on that side
+func (db *DB) Range(start, end []byte) *Iterator {
+	return db.newIterator(nil, inRange(start, end))
+}
+
+func (db *DB) newIterator(snap *Snapshot, opts ...IteratorOption) *Iterator {
+	var o iteratorOptions
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Iterator{
+		db:   db,
+		snap: snap,
+		opts: o,
+	}
+}
+
+func inRange(start, end []byte) IteratorOption {
+	return func(o *iteratorOptions) {
+		o.sorted = true
+		o.start = string(start)
+
+		if end != nil {
+			e := string(end)
+			o.end = &e
+		}
+	}
+}
+
+// prefixEnd returns the smallest key greater than all keys starting with prefix,
+// or nil if there is no such key
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte(nil), prefix...)
+
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+
+			return end[:i+1]
+		}
+	}
+
+	return nil
+}
+
+// Next moves the iterator to the next key and reports whether there is one
+func (it *Iterator) Next() bool {
+	if it.closed || it.err != nil {
+		return false
+	}
+
+	it.db.m.RLock()
+	defer it.db.m.RUnlock()
+
+	if it.snap != nil {
+		if it.snap.released {
+			it.err = ErrSnapshotReleased
+
+			return false
+		}
+
+		return it.nextSorted(it.snap.kd, it.snap.overlay, it.snap.now)
+	}
+
+	now := it.db.time.NowUnix()
+
+	if it.opts.sorted {
+		return it.nextSorted(it.db.kd, nil, now)
+	}
+
+	slot, pos, ok := it.db.kd.next(it.pos, now)
+
+	it.pos = pos
+
+	if !ok {
+		return false
+	}
+
+	it.key = []byte(slot.key)
+	it.entry = slot.entry
+
+	return true
+}
+
+// nextSorted resumes from the key after the current one, so keys inserted or removed behind the iterator
+// do not affect its position. Cursor is started anew once the keydir is reloaded (see Refresh)
+func (it *Iterator) nextSorted(kd *keyDir, o *kdOverlay, now uint32) bool {
+	if it.cursor == nil || it.cursor.kd != kd {
+		from := it.opts.start
+
+		if it.key != nil {
+			// smallest key greater than the current one
+			from = string(it.key) + "\x00"
+		}
+
+		it.cursor = kd.cursor(from, it.opts.end, o)
+	}
+
+	slot, ok := it.cursor.next(now)
+	if !ok {
+		return false
+	}
+
+	it.key = []byte(slot.key)
+	it.entry = slot.entry
+
+	return true
+}
+
+// Key returns the current key
+func (it *Iterator) Key() []byte {
+	return it.key
+}
+
+// Value reads the value of the current key as it was when the iterator moved to it.
+// Read errors are reported by Err
+func (it *Iterator) Value() []byte {
+	if it.closed || it.err != nil || it.key == nil {
+		return nil
+	}
+
+	it.db.m.RLock()
+	defer it.db.m.RUnlock()
+
+	if it.snap != nil && it.snap.released {
+		it.err = ErrSnapshotReleased
+
+		return nil
+	}
+
+	val, err := it.db.readValue(it.key, it.entry)
+	if err != nil {
+		it.err = err
+
+		return nil
+	}
+
+	return val
+}
+
+// Err returns the first error encountered during iteration
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// Close releases the iterator. Next reports no more keys after the iterator is closed
+func (it *Iterator) Close() error {
+	it.closed = true
+	it.key = nil
+	it.cursor = nil
+
+	return nil
+}
+
+// Fold calls fn for every key/value pair in the database until fn returns an error which is then returned by Fold
+func (db *DB) Fold(fn func(key, val []byte) error, opts ...IteratorOption) error {
+	return fold(db.Iterator(opts...), fn)
+}
+
+func fold(it *Iterator, fn func(key, val []byte) error) error {
+	defer it.Close()
+
+	for it.Next() {
+		val := it.Value()
+		if it.Err() != nil {
+			return it.Err()
+		}
+
+		err := fn(it.Key(), val)
+		if err != nil {
+			return err
+		}
+	}
+
+	return it.Err()
+}
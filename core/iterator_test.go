@@ -0,0 +1,278 @@
+package core_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aneshas/gocask/core"
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"testing"
+)
+
+func TestIterator_Should_Visit_All_Keys_And_Values(t *testing.T) {
+	db := getInMemDB(t)
+
+	defer db.Close()
+
+	want := map[string]string{
+		"foo": "foo val",
+		"bar": "bar val",
+		"baz": "baz val",
+	}
+
+	for k, v := range want {
+		_, _ = db.Put([]byte(k), []byte(v))
+	}
+
+	_, _ = db.Put([]byte("deleted"), []byte("val"))
+	_ = db.Delete([]byte("deleted"))
+
+	got := make(map[string]string)
+
+	it := db.Iterator()
+
+	for it.Next() {
+		got[string(it.Key())] = string(it.Value())
+	}
+
+	assert.NoError(t, it.Err())
+	assert.NoError(t, it.Close())
+	assert.Equal(t, want, got)
+}
+
+func TestIterator_Should_Visit_Keys_In_Order(t *testing.T) {
+	db := getInMemDB(t)
+
+	defer db.Close()
+
+	keys := []string{"foo", "bar", "baz", "a", "foobar"}
+
+	for _, k := range keys {
+		_, _ = db.Put([]byte(k), []byte("val"))
+	}
+
+	var got []string
+
+	it := db.Iterator(core.Sorted())
+
+	defer it.Close()
+
+	for it.Next() {
+		got = append(got, string(it.Key()))
+	}
+
+	sort.Strings(keys)
+
+	assert.Equal(t, keys, got)
+}
+
+func TestIterator_Should_Tolerate_Writes_During_Iteration(t *testing.T) {
+	db := getInMemDB(t)
+
+	defer db.Close()
+
+	_, _ = db.Put([]byte("foo"), []byte("val"))
+	_, _ = db.Put([]byte("bar"), []byte("val"))
+
+	it := db.Iterator()
+
+	defer it.Close()
+
+	n := 0
+
+	for it.Next() {
+		n++
+
+		assert.NoError(t, db.Delete(it.Key()))
+	}
+
+	assert.NoError(t, it.Err())
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{}, db.Keys())
+}
+
+func TestFold_Should_Fold_Over_All_Key_Value_Pairs(t *testing.T) {
+	db := getInMemDB(t)
+
+	defer db.Close()
+
+	_, _ = db.Put([]byte("foo"), []byte("1"))
+	_, _ = db.Put([]byte("bar"), []byte("22"))
+	_, _ = db.Put([]byte("baz"), []byte("333"))
+
+	var (
+		size int
+		keys []string
+	)
+
+	err := db.Fold(func(key, val []byte) error {
+		keys = append(keys, string(key))
+		size += len(val)
+
+		return nil
+	}, core.Sorted())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bar", "baz", "foo"}, keys)
+	assert.Equal(t, 6, size)
+}
+
+func TestFold_Should_Stop_On_Error(t *testing.T) {
+	db := getInMemDB(t)
+
+	defer db.Close()
+
+	_, _ = db.Put([]byte("foo"), []byte("1"))
+	_, _ = db.Put([]byte("bar"), []byte("2"))
+
+	wantErr := errors.New("an error")
+	n := 0
+
+	err := db.Fold(func(key, val []byte) error {
+		n++
+
+		return wantErr
+	})
+
+	assert.ErrorIs(t, err, wantErr)
+	assert.Equal(t, 1, n)
+}
+
+func TestScan_Should_Visit_Keys_With_Prefix_In_Order(t *testing.T) {
+	db := getInMemDB(t)
+
+	defer db.Close()
+
+	keys := []string{"user:42:profile", "user:4", "user:42:", "user:43:profile", "user:42:avatar", "user:420", "user:42;"}
+
+	for _, k := range keys {
+		_, _ = db.Put([]byte(k), []byte("val"))
+	}
+
+	_, _ = db.Put([]byte("user:42:deleted"), []byte("val"))
+	_ = db.Delete([]byte("user:42:deleted"))
+
+	assert.Equal(t, []string{"user:42:", "user:42:avatar", "user:42:profile"}, collect(t, db.Scan([]byte("user:42:"))))
+}
+
+func TestScan_Should_Handle_Prefix_Of_Max_Bytes(t *testing.T) {
+	db := getInMemDB(t)
+
+	defer db.Close()
+
+	_, _ = db.Put([]byte{0xfe}, []byte("val"))
+	_, _ = db.Put([]byte{0xff}, []byte("val"))
+	_, _ = db.Put([]byte{0xff, 0xff, 0x01}, []byte("val"))
+
+	assert.Equal(t, []string{"\xff", "\xff\xff\x01"}, collect(t, db.Scan([]byte{0xff})))
+}
+
+func TestRange_Should_Visit_Keys_In_Range(t *testing.T) {
+	db := getInMemDB(t)
+
+	defer db.Close()
+
+	for _, k := range []string{"a", "b", "c", "d", "e"} {
+		_, _ = db.Put([]byte(k), []byte("val"))
+	}
+
+	cases := []struct {
+		start, end []byte
+		want       []string
+	}{
+		{[]byte("b"), []byte("d"), []string{"b", "c"}},
+		{[]byte("bb"), []byte("dd"), []string{"c", "d"}},
+		{nil, []byte("c"), []string{"a", "b"}},
+		{[]byte("d"), nil, []string{"d", "e"}},
+		{nil, nil, []string{"a", "b", "c", "d", "e"}},
+		{[]byte("c"), []byte("c"), nil},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, collect(t, db.Range(tc.start, tc.end)))
+	}
+}
+
+func TestRange_Should_Tolerate_Writes_During_Iteration(t *testing.T) {
+	db := getInMemDB(t)
+
+	defer db.Close()
+
+	for _, k := range []string{"a", "c", "e"} {
+		_, _ = db.Put([]byte(k), []byte("val"))
+	}
+
+	it := db.Range(nil, nil)
+
+	defer it.Close()
+
+	var got []string
+
+	for it.Next() {
+		got = append(got, string(it.Key()))
+
+		assert.NoError(t, db.Delete(it.Key()))
+
+		if string(it.Key()) == "a" {
+			_, _ = db.Put([]byte("d"), []byte("val"))
+			_, _ = db.Put([]byte("0"), []byte("val"))
+		}
+	}
+
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []string{"a", "c", "d", "e"}, got)
+}
+
+func TestRange_Should_Visit_Keys_Written_Ahead_Of_Iteration(t *testing.T) {
+	db := getInMemDB(t)
+
+	defer db.Close()
+
+	// Enough keys for each keydir shard to hold more of them than is read ahead at once
+	const n = 10000
+
+	var want []string
+
+	for i := 0; i < n; i++ {
+		want = append(want, fmt.Sprintf("k%05d", i))
+
+		if i%2 == 0 {
+			_, _ = db.Put([]byte(want[i]), []byte("val"))
+		}
+	}
+
+	it := db.Range(nil, nil)
+
+	defer it.Close()
+
+	var got []string
+
+	for it.Next() {
+		got = append(got, string(it.Key()))
+
+		if len(got) == 1 {
+			for i := 1; i < n; i += 2 {
+				_, _ = db.Put([]byte(want[i]), []byte("val"))
+			}
+		}
+	}
+
+	assert.NoError(t, it.Err())
+	assert.Equal(t, want, got)
+}
+
+func collect(t *testing.T, it *core.Iterator) []string {
+	defer it.Close()
+
+	var keys []string
+
+	for it.Next() {
+		keys = append(keys, string(it.Key()))
+
+		assert.Equal(t, "val", string(it.Value()))
+	}
+
+	assert.NoError(t, it.Err())
+
+	return keys
+}
@@ -0,0 +1,170 @@
+package core
+
+import (
+	"container/heap"
+	"sync/atomic"
+)
+
+// kdReadAhead is the number of slots which are read ahead from the ordered index of a shard at once
+const kdReadAhead = 64
+
+// kdCursor visits the live slots of the keydir in ascending key order as seen through the overlay
+// (nil overlay sees the current state). Shards are ordered independently, so the cursor merges them.
+// It reads a few slots of each shard ahead, so that the shard indexes are not searched upon every step.
+// Shard which was written to since it was read is read again (see kdShard.writes), so that
+// the slots which were read ahead are never stale
+type kdCursor struct {
+	kd     *keyDir
+	o      *kdOverlay
+	end    *string
+	shards [kdShards]kdShardCursor
+
+	// heads are the shards which have slots left ordered by their first key
+	heads kdHeads
+
+	// from is the smallest key which may be visited unless some key was visited already,
+	// in which case the following keys are greater than the last one
+	from    string
+	last    string
+	visited bool
+}
+
+type kdShardCursor struct {
+	shard  int
+	slots  []kdSlot
+	writes uint32
+}
+
+// cursor creates a cursor positioned before the first key at or after from and before end (if given)
+func (kd *keyDir) cursor(from string, end *string, o *kdOverlay) *kdCursor {
+	c := kdCursor{
+		kd:   kd,
+		o:    o,
+		end:  end,
+		from: from,
+	}
+
+	for si := range c.shards {
+		c.shards[si].shard = si
+		c.read(si)
+	}
+
+	c.order()
+
+	return &c
+}
+
+// next returns the following live slot
+func (c *kdCursor) next(now uint32) (kdSlot, bool) {
+	c.refresh()
+
+	for len(c.heads) > 0 {
+		sc := c.heads[0]
+
+		slot := sc.slots[0]
+
+		sc.slots = sc.slots[1:]
+
+		c.last, c.visited = slot.key, true
+
+		if len(sc.slots) == 0 {
+			c.read(sc.shard)
+		}
+
+		if len(sc.slots) == 0 {
+			heap.Pop(&c.heads)
+		} else {
+			heap.Fix(&c.heads, 0)
+		}
+
+		if slot.entry.isExpired(now) {
+			continue
+		}
+
+		return slot, true
+	}
+
+	return kdSlot{}, false
+}
+
+// refresh reads the shards which were written to since they were read
+func (c *kdCursor) refresh() {
+	stale := false
+
+	for si := range c.shards {
+		if atomic.LoadUint32(&c.kd.shards[si].writes) != c.shards[si].writes {
+			c.read(si)
+
+			stale = true
+		}
+	}
+
+	if stale {
+		c.order()
+	}
+}
+
+// read reads ahead the slots of the shard which are yet to be visited
+func (c *kdCursor) read(si int) {
+	var part *kdOverlayPart
+
+	if c.o != nil {
+		part = &c.o.parts[si]
+	}
+
+	from := c.from
+
+	if c.visited {
+		// smallest key greater than the last one
+		from = c.last + "\x00"
+	}
+
+	sc := &c.shards[si]
+	s := &c.kd.shards[si]
+
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	sc.writes = atomic.LoadUint32(&s.writes)
+	sc.slots = s.readAhead(from, c.end, part)
+}
+
+func (c *kdCursor) order() {
+	c.heads = c.heads[:0]
+
+	for si := range c.shards {
+		if len(c.shards[si].slots) > 0 {
+			c.heads = append(c.heads, &c.shards[si])
+		}
+	}
+
+	heap.Init(&c.heads)
+}
+
+// kdHeads is a min-heap of shard cursors ordered by their first key
+type kdHeads []*kdShardCursor
+
+func (h kdHeads) Len() int {
+	return len(h)
+}
+
+func (h kdHeads) Less(i, j int) bool {
+	return h[i].slots[0].key < h[j].slots[0].key
+}
+
+func (h kdHeads) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *kdHeads) Push(x interface{}) {
+	*h = append(*h, x.(*kdShardCursor))
+}
+
+func (h *kdHeads) Pop() interface{} {
+	old := *h
+	n := len(old)
+	sc := old[n-1]
+	*h = old[:n-1]
+
+	return sc
+}
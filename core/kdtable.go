@@ -0,0 +1,110 @@
+package core
+
+import (
+	"bytes"
+	"hash/maphash"
+)
+
+// kdTable maps keys to positions of their keydir items. It's an open addressing hash table
+// (with linear probing) of item positions, so unlike a map it takes just a few bytes per key.
+// Buckets hold item position + 1, zero signifies an empty bucket
+type kdTable struct {
+	seed    maphash.Seed
+	buckets []uint32
+	len     int
+}
+
+func hashKey(seed maphash.Seed, key []byte) uint64 {
+	var h maphash.Hash
+
+	h.SetSeed(seed)
+
+	_, _ = h.Write(key)
+
+	return h.Sum64()
+}
+
+// find returns the item position of the key (whose hash is h) along with its bucket
+func (t *kdTable) find(key []byte, h uint64, items []kdItem, arena []byte) (int, int, bool) {
+	if len(t.buckets) == 0 {
+		return 0, 0, false
+	}
+
+	mask := len(t.buckets) - 1
+
+	for b := int(h) & mask; ; b = (b + 1) & mask {
+		pos := t.buckets[b]
+		if pos == 0 {
+			return 0, b, false
+		}
+
+		if bytes.Equal(items[pos-1].key.in(arena), key) {
+			return int(pos - 1), b, true
+		}
+	}
+}
+
+// insert adds the item (whose key hash is h) which is not in the table yet. The table is grown once it's half full
+func (t *kdTable) insert(h uint64, i int, items []kdItem, arena []byte) {
+	if (t.len+1)*2 > len(t.buckets) {
+		t.grow(items, arena)
+	}
+
+	t.place(h, uint32(i+1))
+
+	t.len++
+}
+
+func (t *kdTable) place(h uint64, pos uint32) {
+	mask := len(t.buckets) - 1
+
+	b := int(h) & mask
+
+	for t.buckets[b] != 0 {
+		b = (b + 1) & mask
+	}
+
+	t.buckets[b] = pos
+}
+
+func (t *kdTable) grow(items []kdItem, arena []byte) {
+	n := 2 * len(t.buckets)
+	if n == 0 {
+		n = 16
+	}
+
+	old := t.buckets
+
+	t.buckets = make([]uint32, n)
+
+	for _, pos := range old {
+		if pos != 0 {
+			t.place(hashKey(t.seed, items[pos-1].key.in(arena)), pos)
+		}
+	}
+}
+
+// delete empties the bucket and shifts back the following keys of the probe sequence
+// which would not be found otherwise (so that no tombstones are needed)
+func (t *kdTable) delete(b int, items []kdItem, arena []byte) {
+	mask := len(t.buckets) - 1
+
+	t.buckets[b] = 0
+	t.len--
+
+	for j := (b + 1) & mask; t.buckets[j] != 0; j = (j + 1) & mask {
+		pos := t.buckets[j]
+
+		home := int(hashKey(t.seed, items[pos-1].key.in(arena))) & mask
+
+		// The key stays if its home bucket lies cyclically within (b, j]
+		if b < j && b < home && home <= j || b > j && (b < home || home <= j) {
+			continue
+		}
+
+		t.buckets[b] = pos
+		t.buckets[j] = 0
+
+		b = j
+	}
+}
@@ -1,70 +1,631 @@
 package core
 
+import (
+	"bytes"
+	"github.com/aneshas/gocask/internal/btree"
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// kdShardBits is the number of (top) key hash bits which select the keydir shard of the key
+	kdShardBits = 5
+	kdShards    = 1 << kdShardBits
+
+	// kdKeySizeBits is the number of (low) bits of kdKey which hold the key size (see maxKeySize).
+	// The rest of the bits hold the key offset, so the key arena of a shard holds up to 32GiB of keys
+	kdKeySizeBits = 29
+)
+
 type kdEntry struct {
 	CRC       uint32
 	Timestamp uint32
-	ValuePos  uint32
+	ValuePos  uint64
 	ValueSize uint32
+	Expiry    uint32
+	Seq       uint64
 	File      string
 }
 
+func (ke kdEntry) isExpired(now uint32) bool {
+	return ke.Expiry != 0 && ke.Expiry <= now
+}
+
+// kdSlot holds a single keydir entry along with its key.
+// Empty entry signifies a key which does not exist
+type kdSlot struct {
+	key   string
+	entry kdEntry
+}
+
+// kdRecord is the packed form in which keydir entries are kept in memory.
+// Data file names are interned, so the record refers to its data file by id
+type kdRecord struct {
+	valuePos  uint64
+	seq       uint64
+	crc       uint32
+	timestamp uint32
+	valueSize uint32
+	expiry    uint32
+	file      uint32
+}
+
+func (rec kdRecord) isExpired(now uint32) bool {
+	return rec.expiry != 0 && rec.expiry <= now
+}
+
+// kdKey refers to a key kept in the key arena of its shard by the key offset and size.
+// Zero key refers to no key (keys can not be empty)
+type kdKey uint64
+
+func newKDKey(offset, size int) kdKey {
+	return kdKey(offset)<<kdKeySizeBits | kdKey(size)
+}
+
+func (k kdKey) size() int {
+	return int(k & (1<<kdKeySizeBits - 1))
+}
+
+// in returns the key from the arena
+func (k kdKey) in(arena []byte) []byte {
+	offset := int(k >> kdKeySizeBits)
+
+	return arena[offset : offset+k.size()]
+}
+
+// kdItem holds a single keydir record along with its key. Zero key signifies a free item
+type kdItem struct {
+	key kdKey
+	rec kdRecord
+}
+
+// keyDir maps keys to their entries. Keys are partitioned by hash into shards which are guarded
+// by their own locks, so that looking up a key does not wait for the keys of other shards to be written.
+// lastOffset is the position of the next entry in the active data file and it's guarded by the db append lock.
+// Overlays are added and removed only while the db is locked exclusively
 type keyDir struct {
-	lastOffset uint32
-	entries    map[string]kdEntry
+	lastOffset uint64
+	seed       maphash.Seed
+	shards     [kdShards]kdShard
+	overlays   []*kdOverlay
+}
+
+// kdShard maps keys to items holding their records. Items are kept in a slice so that the shard
+// can be iterated over by item position without holding its lock for the whole iteration,
+// and they are looked up by key through a compact hash table of item positions.
+// Keys are stored back to back in a single arena instead of being allocated one by one, and the arena
+// is compacted once removed keys take more than half of it.
+// Item positions are additionally kept in an index ordered by their keys which backs sorted iteration,
+// prefix scans and range queries.
+// writes counts the modifications of the shard, so that cursors can tell when the slots they read ahead are stale
+type kdShard struct {
+	m       sync.RWMutex
+	table   kdTable
+	items   []kdItem
+	free    []uint32
+	arena   []byte
+	garbage int
+	files   []string
+	fileIDs map[string]uint32
+	index   *btree.BTree
+	writes  uint32
+}
+
+// kdPos is the position of an item in the keydir
+type kdPos struct {
+	shard int
+	item  int
+}
+
+// kdOverlay preserves keydir entries as they were when a snapshot was taken.
+// An entry is copied into the overlay before it is modified for the first time,
+// nil entry signifies a key which did not exist at that time.
+// Overlay is partitioned like the keydir and each part is guarded by the lock of its shard
+type kdOverlay struct {
+	parts [kdShards]kdOverlayPart
+}
+
+// kdOverlayPart holds the preserved entries of a single shard. Preserved keys are indexed by their position
+type kdOverlayPart struct {
+	entries map[string]*kdEntry
+	keys    []string
+	index   *btree.BTree
 }
 
 func newKeyDir() *keyDir {
-	return &keyDir{
-		entries: map[string]kdEntry{},
+	kd := keyDir{
+		seed: maphash.MakeSeed(),
+	}
+
+	for i := range kd.shards {
+		s := &kd.shards[i]
+
+		s.table = kdTable{seed: kd.seed}
+		s.fileIDs = map[string]uint32{}
+		s.index = btree.New(s.less)
 	}
+
+	return &kd
+}
+
+// locate returns the shard of the key along with the key hash
+func (kd *keyDir) locate(key []byte) (int, uint64) {
+	h := hashKey(kd.seed, key)
+
+	return int(h >> (64 - kdShardBits)), h
 }
 
 func (kd *keyDir) set(key []byte, h header, file string) {
 	entry := kdEntry{
 		CRC:       h.CRC,
-		ValuePos:  kd.lastOffset + h.entrySize() - h.ValueSize,
+		ValuePos:  kd.lastOffset + h.entrySize() - uint64(h.ValueSize),
 		ValueSize: h.ValueSize,
 		Timestamp: h.Timestamp,
+		Expiry:    h.Expiry,
+		Seq:       h.Seq,
 		File:      file,
 	}
 
 	kd.lastOffset = kd.lastOffset + h.entrySize()
 
-	kd.entries[string(key)] = entry
+	kd.setEntry(key, entry)
 }
 
 func (kd *keyDir) get(key []byte) (kdEntry, error) {
-	ke, ok := kd.entries[string(key)]
-	if !ok {
-		return kdEntry{}, ErrKeyNotFound
+	si, h := kd.locate(key)
+
+	s := &kd.shards[si]
+
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	return s.get(key, h)
+}
+
+func (kd *keyDir) setEntry(key []byte, entry kdEntry) {
+	si, h := kd.locate(key)
+
+	s := &kd.shards[si]
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	atomic.AddUint32(&s.writes, 1)
+
+	i, _, ok := s.table.find(key, h, s.items, s.arena)
+	if ok {
+		kd.preserve(si, key, s.entry(s.items[i].rec))
+		s.items[i].rec = s.record(entry)
+
+		return
 	}
 
-	return ke, nil
+	kd.preserve(si, key, kdEntry{})
+
+	item := kdItem{
+		key: s.store(key),
+		rec: s.record(entry),
+	}
+
+	if n := len(s.free); n > 0 {
+		i = int(s.free[n-1])
+		s.free = s.free[:n-1]
+		s.items[i] = item
+	} else {
+		i = len(s.items)
+		s.items = append(s.items, item)
+	}
+
+	s.table.insert(h, i, s.items, s.arena)
+	s.index.Insert(uint32(i))
+}
+
+func (kd *keyDir) move(key []byte, from, to kdEntry) {
+	si, h := kd.locate(key)
+
+	s := &kd.shards[si]
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	i, _, ok := s.table.find(key, h, s.items, s.arena)
+	if !ok || s.entry(s.items[i].rec) != from {
+		return
+	}
+
+	atomic.AddUint32(&s.writes, 1)
+
+	s.items[i].rec = s.record(to)
 }
 
-func (kd *keyDir) unset(key []byte) {
-	delete(kd.entries, string(key))
+// drop removes the key only if it still points to the given entry
+func (kd *keyDir) drop(key []byte, entry kdEntry) {
+	kd.remove(key, &entry)
+}
+
+// unset removes the key of the tombstone with the given header
+func (kd *keyDir) unset(key []byte, h header) {
+	kd.remove(key, nil)
+
+	kd.lastOffset = kd.lastOffset + h.entrySize()
+}
+
+// remove removes the key if it points to the given entry (or regardless of its entry if nil)
+func (kd *keyDir) remove(key []byte, entry *kdEntry) {
+	si, h := kd.locate(key)
+
+	s := &kd.shards[si]
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	i, b, ok := s.table.find(key, h, s.items, s.arena)
+	if !ok || entry != nil && s.entry(s.items[i].rec) != *entry {
+		return
+	}
+
+	atomic.AddUint32(&s.writes, 1)
+
+	kd.preserve(si, key, s.entry(s.items[i].rec))
 
-	kd.lastOffset = kd.lastOffset + headerSize + uint32(len(key))
+	// Item is removed from the table and the index while it still refers to its key
+	s.table.delete(b, s.items, s.arena)
+	s.index.Delete(uint32(i))
+
+	s.garbage += s.items[i].key.size()
+
+	s.items[i] = kdItem{}
+	s.free = append(s.free, uint32(i))
+
+	if s.garbage > len(s.arena)/2 {
+		s.compact()
+	}
 }
 
 func (kd *keyDir) resetOffset() {
 	kd.lastOffset = 0
 }
 
-func (kd *keyDir) advanceOffsetBy(n uint32) {
+func (kd *keyDir) advanceOffsetBy(n uint64) {
 	kd.lastOffset += n
 }
 
-func (kd *keyDir) keys() []string {
-	// This duplicates all keys allocates a lot of memory potentially exhausting it - does it make sense?
-	// Stream values instead?
+// newOverlay starts preserving the current state of the keydir
+func (kd *keyDir) newOverlay() *kdOverlay {
+	var o kdOverlay
+
+	for i := range o.parts {
+		part := &o.parts[i]
+
+		part.entries = map[string]*kdEntry{}
+		part.index = btree.New(part.less)
+	}
+
+	kd.overlays = append(kd.overlays, &o)
+
+	return &o
+}
+
+func (kd *keyDir) removeOverlay(o *kdOverlay) {
+	for i, ov := range kd.overlays {
+		if ov == o {
+			kd.overlays = append(kd.overlays[:i], kd.overlays[i+1:]...)
+
+			return
+		}
+	}
+}
+
+// preserve copies the entry of the key into the overlays which have not preserved the key yet.
+// Empty entry is preserved as a key which did not exist.
+// It's called with the lock of the shard held
+func (kd *keyDir) preserve(shard int, key []byte, entry kdEntry) {
+	if len(kd.overlays) == 0 {
+		return
+	}
+
+	var preserved *kdEntry
+
+	if (entry != kdEntry{}) {
+		e := entry
+		preserved = &e
+	}
+
+	for _, o := range kd.overlays {
+		part := &o.parts[shard]
+
+		if _, ok := part.entries[string(key)]; ok {
+			continue
+		}
 
+		k := string(key)
+
+		part.entries[k] = preserved
+		part.keys = append(part.keys, k)
+		part.index.Insert(uint32(len(part.keys) - 1))
+	}
+}
+
+// lookup returns the entry of the key as seen through the overlay. Nil overlay sees the current state
+func (kd *keyDir) lookup(key []byte, o *kdOverlay) (kdEntry, error) {
+	si, h := kd.locate(key)
+
+	s := &kd.shards[si]
+
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	if o != nil {
+		entry, ok := o.parts[si].entries[string(key)]
+		if ok {
+			if entry == nil {
+				return kdEntry{}, ErrKeyNotFound
+			}
+
+			return *entry, nil
+		}
+	}
+
+	return s.get(key, h)
+}
+
+// next returns the first live slot at or after the position along with the position following it
+func (kd *keyDir) next(pos kdPos, now uint32) (kdSlot, kdPos, bool) {
+	for ; pos.shard < kdShards; pos = (kdPos{shard: pos.shard + 1}) {
+		s := &kd.shards[pos.shard]
+
+		s.m.RLock()
+		slot, i, ok := s.next(pos.item, now)
+		s.m.RUnlock()
+
+		if ok {
+			return slot, kdPos{shard: pos.shard, item: i + 1}, true
+		}
+	}
+
+	return kdSlot{}, pos, false
+}
+
+func (kd *keyDir) keys(now uint32) []string {
 	keys := []string{}
 
-	for key := range kd.entries {
-		keys = append(keys, key)
+	for i := range kd.shards {
+		s := &kd.shards[i]
+
+		s.m.RLock()
+
+		for _, item := range s.items {
+			if item.key == 0 || item.rec.isExpired(now) {
+				continue
+			}
+
+			keys = append(keys, string(item.key.in(s.arena)))
+		}
+
+		s.m.RUnlock()
 	}
 
 	return keys
 }
+
+func (s *kdShard) get(key []byte, h uint64) (kdEntry, error) {
+	i, _, ok := s.table.find(key, h, s.items, s.arena)
+	if !ok {
+		return kdEntry{}, ErrKeyNotFound
+	}
+
+	return s.entry(s.items[i].rec), nil
+}
+
+// key returns the key of the item at position i
+func (s *kdShard) key(i uint32) []byte {
+	return s.items[i].key.in(s.arena)
+}
+
+// less orders item positions by their keys
+func (s *kdShard) less(a, b uint32) bool {
+	return bytes.Compare(s.key(a), s.key(b)) < 0
+}
+
+// slot returns the item at position i along with its entry
+func (s *kdShard) slot(i int) kdSlot {
+	return kdSlot{
+		key:   string(s.key(uint32(i))),
+		entry: s.entry(s.items[i].rec),
+	}
+}
+
+// store appends the key to the arena
+func (s *kdShard) store(key []byte) kdKey {
+	k := newKDKey(len(s.arena), len(key))
+
+	s.arena = append(s.arena, key...)
+
+	return k
+}
+
+// compact moves the keys of the items back to back into a new arena, leaving out the removed keys
+func (s *kdShard) compact() {
+	arena := make([]byte, 0, len(s.arena)-s.garbage)
+
+	for i, item := range s.items {
+		if item.key == 0 {
+			continue
+		}
+
+		key := item.key.in(s.arena)
+
+		s.items[i].key = newKDKey(len(arena), len(key))
+
+		arena = append(arena, key...)
+	}
+
+	s.arena = arena
+	s.garbage = 0
+}
+
+// record packs the entry, interning the name of its data file
+func (s *kdShard) record(entry kdEntry) kdRecord {
+	id, ok := s.fileIDs[entry.File]
+	if !ok {
+		id = uint32(len(s.files))
+
+		s.files = append(s.files, entry.File)
+		s.fileIDs[entry.File] = id
+	}
+
+	return kdRecord{
+		valuePos:  entry.ValuePos,
+		seq:       entry.Seq,
+		crc:       entry.CRC,
+		timestamp: entry.Timestamp,
+		valueSize: entry.ValueSize,
+		expiry:    entry.Expiry,
+		file:      id,
+	}
+}
+
+func (s *kdShard) entry(rec kdRecord) kdEntry {
+	return kdEntry{
+		CRC:       rec.crc,
+		Timestamp: rec.timestamp,
+		ValuePos:  rec.valuePos,
+		ValueSize: rec.valueSize,
+		Expiry:    rec.expiry,
+		Seq:       rec.seq,
+		File:      s.files[rec.file],
+	}
+}
+
+// next returns the first live slot at or after position i along with its position
+func (s *kdShard) next(i int, now uint32) (kdSlot, int, bool) {
+	for ; i < len(s.items); i++ {
+		if s.items[i].key == 0 || s.items[i].rec.isExpired(now) {
+			continue
+		}
+
+		return s.slot(i), i, true
+	}
+
+	return kdSlot{}, i, false
+}
+
+// readAhead returns up to kdReadAhead live slots of the shard (as seen through the overlay part)
+// whose keys are at or after from and before end (if given). Expired slots are returned as well.
+// No slots are returned only once there are no live slots left
+func (s *kdShard) readAhead(from string, end *string, part *kdOverlayPart) []kdSlot {
+	for {
+		slots := s.read(from, end)
+
+		if part != nil {
+			slots = mergeSlots(part.view(slots), part.read(from, end))
+		}
+
+		live := make([]kdSlot, 0, len(slots))
+
+		for _, slot := range slots {
+			if (slot.entry != kdEntry{}) {
+				live = append(live, slot)
+			}
+		}
+
+		if len(live) > 0 || len(slots) < kdReadAhead {
+			return live
+		}
+
+		// smallest key greater than the last one
+		from = slots[len(slots)-1].key + "\x00"
+	}
+}
+
+// read returns up to kdReadAhead slots of the shard whose keys are at or after from and before end (if given)
+func (s *kdShard) read(from string, end *string) []kdSlot {
+	var slots []kdSlot
+
+	s.index.Ascend(func(i uint32) bool {
+		return string(s.key(i)) < from
+	}, func(i uint32) bool {
+		key := s.key(i)
+
+		if end != nil && string(key) >= *end {
+			return false
+		}
+
+		slots = append(slots, kdSlot{key: string(key), entry: s.entry(s.items[i].rec)})
+
+		return len(slots) < kdReadAhead
+	})
+
+	return slots
+}
+
+// view replaces the entries of the slots with those preserved by the overlay part
+func (part *kdOverlayPart) view(slots []kdSlot) []kdSlot {
+	for i := range slots {
+		entry, ok := part.entries[slots[i].key]
+		if !ok {
+			continue
+		}
+
+		slots[i].entry = kdEntry{}
+
+		if entry != nil {
+			slots[i].entry = *entry
+		}
+	}
+
+	return slots
+}
+
+// read returns up to kdReadAhead preserved slots whose keys are at or after from and before end (if given).
+// Keys which did not exist when the overlay was created are returned with empty entries
+func (part *kdOverlayPart) read(from string, end *string) []kdSlot {
+	var slots []kdSlot
+
+	part.index.Ascend(func(i uint32) bool {
+		return part.keys[i] < from
+	}, func(i uint32) bool {
+		key := part.keys[i]
+
+		if end != nil && key >= *end {
+			return false
+		}
+
+		slot := kdSlot{key: key}
+
+		if entry := part.entries[key]; entry != nil {
+			slot.entry = *entry
+		}
+
+		slots = append(slots, slot)
+
+		return len(slots) < kdReadAhead
+	})
+
+	return slots
+}
+
+// less orders the positions of preserved keys by the keys
+func (part *kdOverlayPart) less(a, b uint32) bool {
+	return part.keys[a] < part.keys[b]
+}
+
+// mergeSlots merges two sets of slots ordered by their keys, keeping up to kdReadAhead of the smallest ones.
+// Slots of the same key hold the same entry
+func mergeSlots(a, b []kdSlot) []kdSlot {
+	slots := make([]kdSlot, 0, kdReadAhead)
+
+	for len(slots) < kdReadAhead && (len(a) > 0 || len(b) > 0) {
+		switch {
+		case len(b) == 0 || len(a) > 0 && a[0].key < b[0].key:
+			slots, a = append(slots, a[0]), a[1:]
+		case len(a) == 0 || b[0].key < a[0].key:
+			slots, b = append(slots, b[0]), b[1:]
+		default:
+			slots, a, b = append(slots, a[0]), a[1:], b[1:]
+		}
+	}
+
+	return slots
+}
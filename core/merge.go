@@ -0,0 +1,313 @@
+package core
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Merge compacts all immutable data files by rewriting only the live entries
+// into new data files and removing the old ones, reclaiming the space taken by
+// overwritten and deleted keys. Reads and writes are served while the merge is in progress.
+// Merge fails with ErrCRCFailed upon a corrupted live entry and with ErrTornTail upon an incomplete entry
+// (which was skipped upon startup), in which case the data file should be salvaged with Repair first
+func (db *DB) Merge() error {
+	if db.cfg.ReadOnly {
+		return ErrReadOnly
+	}
+
+	db.mm.Lock()
+	defer db.mm.Unlock()
+
+	active, files, err := db.immutableFiles()
+	if err != nil {
+		return err
+	}
+
+	if len(files) == 0 {
+		return nil
+	}
+
+	m := merger{
+		db:     db,
+		active: active,
+		now:    db.time.NowUnix(),
+		seq:    db.lastSeq(),
+	}
+
+	err = db.fs.Walk(db.path, func(file File) error {
+		if !files[file.Name()] {
+			return nil
+		}
+
+		return m.mergeFile(file)
+	})
+	if err != nil {
+		_ = m.close()
+
+		return fmt.Errorf("gocask: merge error: %w", err)
+	}
+
+	err = m.close()
+	if err != nil {
+		return fmt.Errorf("gocask: merge error: %w", err)
+	}
+
+	return db.commitMerge(&m, files)
+}
+
+func (db *DB) immutableFiles() (string, map[string]bool, error) {
+	db.m.RLock()
+	defer db.m.RUnlock()
+
+	db.wm.Lock()
+	active := db.file.Name()
+	db.wm.Unlock()
+
+	names, err := db.fs.Files(db.path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	files := make(map[string]bool)
+
+	for _, name := range names {
+		// Files retained for snapshots were merged already
+		if name != active && db.retired[name] == nil {
+			files[name] = true
+		}
+	}
+
+	return active, files, nil
+}
+
+func (db *DB) commitMerge(m *merger, files map[string]bool) error {
+	db.m.Lock()
+	defer db.m.Unlock()
+
+	err := db.fs.CommitMerge(db.path, m.files)
+	if err != nil {
+		return fmt.Errorf("gocask: merge error: %w", err)
+	}
+
+	for _, mv := range m.moves {
+		db.kd.move(mv.key, mv.from, mv.to)
+	}
+
+	for _, mv := range m.expired {
+		db.kd.drop(mv.key, mv.from)
+	}
+
+	merged := make([]string, 0, len(files))
+
+	for file := range files {
+		merged = append(merged, file)
+	}
+
+	// Merged files are retained as a whole along with the tombstones they hold, and files retained
+	// for a later merge are released no sooner than the earlier ones, so deleted keys do not reappear
+	// from the retained files upon startup if the database was not closed properly
+	err = db.retire(merged)
+	if err != nil {
+		return fmt.Errorf("gocask: merge error: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DB) liveEntry(key []byte, file string, valuePos uint64) (kdEntry, bool) {
+	db.m.RLock()
+	defer db.m.RUnlock()
+
+	ke, err := db.kd.get(key)
+	if err != nil {
+		return kdEntry{}, false
+	}
+
+	return ke, ke.File == file && ke.ValuePos == valuePos
+}
+
+type move struct {
+	key      []byte
+	from, to kdEntry
+}
+
+type merger struct {
+	db     *DB
+	active string
+	file   File
+	hint   *hintWriter
+	offset uint64
+	now    uint32
+	seq    uint64
+	files  []string
+	moves  []move
+
+	// expired holds keys which were dropped by the merge due to expiry
+	expired []move
+}
+
+func (m *merger) mergeFile(file File) error {
+	r := bufio.NewReader(file)
+
+	fh, err := readFileHeader(r)
+	if err != nil {
+		if errors.Is(err, errEmptyFile) || errors.Is(err, errTornFileHeader) {
+			return nil
+		}
+
+		return err
+	}
+
+	return m.mergeEntries(newEntryReader(r, uint64(fh.size()), fh.valueCRC(), true), file.Name())
+}
+
+func (m *merger) mergeEntries(er *entryReader, name string) error {
+	for {
+		e, err := er.next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		// Merged data files are removed, so the entries which can not be read are not dropped along with them
+		if err != nil && !isCorrupted(err) {
+			if isTorn(err) {
+				return fmt.Errorf("%w: %v at offset %d", ErrTornTail, name, er.offset)
+			}
+
+			return err
+		}
+
+		if e.h.isTombstone() {
+			continue
+		}
+
+		ke, live := m.db.liveEntry(e.key, name, e.valuePos())
+		if !live {
+			continue
+		}
+
+		// Corrupted value is not signed again, so that it's still reported upon read (and by verify)
+		if err != nil {
+			return fmt.Errorf("%w: entry of %v at offset %d", ErrCRCFailed, name, e.offset)
+		}
+
+		if ke.isExpired(m.now) {
+			m.expired = append(m.expired, move{
+				key:  e.key,
+				from: ke,
+			})
+
+			continue
+		}
+
+		err = m.write(e.h, e.key, e.val, ke)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (m *merger) write(h header, key, val []byte, from kdEntry) error {
+	// Entries of data files written in the older format carry crc of the value only, so the checked entry is signed again
+	h.CRC = h.checksum(key, val)
+
+	err := m.rotate(int64(h.entrySize()))
+	if err != nil {
+		return err
+	}
+
+	_, err = m.file.Write(serializeEntry(h, key, val))
+	if err != nil {
+		return err
+	}
+
+	to := from
+
+	to.CRC = h.CRC
+	to.File = m.file.Name()
+	to.ValuePos = m.offset + h.entrySize() - uint64(h.ValueSize)
+
+	m.offset += h.entrySize()
+
+	err = m.hint.write(to, key)
+	if err != nil {
+		return err
+	}
+
+	m.moves = append(m.moves, move{
+		key:  key,
+		from: from,
+		to:   to,
+	})
+
+	return nil
+}
+
+func (m *merger) rotate(entrySz int64) error {
+	if m.file != nil && (m.file.Size()+entrySz) <= m.db.cfg.MaxDataFileSize {
+		return nil
+	}
+
+	err := m.close()
+	if err != nil {
+		return err
+	}
+
+	m.file, err = m.db.fs.CreateMergeFile(m.db.path, m.active)
+	if err != nil {
+		return err
+	}
+
+	fh := newFileHeader(m.now, m.seq)
+
+	_, err = m.file.Write(fh.encode())
+	if err != nil {
+		return err
+	}
+
+	hf, err := m.db.fs.CreateHintFile(m.db.path, m.file.Name())
+	if err != nil {
+		return err
+	}
+
+	m.hint, err = newHintWriter(hf)
+	if err != nil {
+		return err
+	}
+	m.offset = uint64(fh.size())
+	m.files = append(m.files, m.file.Name())
+
+	return nil
+}
+
+func (m *merger) close() error {
+	if m.file == nil {
+		return nil
+	}
+
+	// Merged files are always flushed since old data files are removed once the merge is committed
+	err := m.file.Sync()
+	if err == nil {
+		err = m.file.Close()
+	} else {
+		_ = m.file.Close()
+	}
+
+	m.file = nil
+
+	if m.hint == nil {
+		return err
+	}
+
+	hErr := m.hint.close()
+
+	m.hint = nil
+
+	if err != nil {
+		return err
+	}
+
+	return hErr
+}
@@ -0,0 +1,163 @@
+package core
+
+import (
+	"bufio"
+	"errors"
+	"io"
+)
+
+// tail keeps track of how far read-only database has read the data files
+type tail struct {
+	files  []string
+	offset uint64
+}
+
+// follows reports whether files listed now are the continuation of the files read so far,
+// which is not the case once a writer merges the data files
+func (t tail) follows(files []string) bool {
+	if len(files) < len(t.files) {
+		return false
+	}
+
+	for i := range t.files {
+		if files[i] != t.files[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func openReadOnly(dbpath string, fs FS, time Time, cfg Config) (*DB, error) {
+	db := DB{
+		cfg:  cfg,
+		time: time,
+		fs:   fs,
+		path: dbpath,
+		kd:   newKeyDir(),
+
+		snaps:    map[*Snapshot]struct{}{},
+		valueCRC: map[string]bool{},
+		cache:    newValueCache(cfg.ValueCacheSize),
+	}
+
+	err := db.Refresh()
+	if err != nil {
+		return nil, err
+	}
+
+	return &db, nil
+}
+
+// Refresh brings keydir of a read-only database up to date by reading the entries
+// written since the database was opened or last refreshed. Keydir is rebuilt from scratch
+// if the writer merged the data files in the meantime.
+// Refresh is a no-op for databases which are not opened in read-only mode
+func (db *DB) Refresh() error {
+	if !db.cfg.ReadOnly {
+		return nil
+	}
+
+	db.m.Lock()
+	defer db.m.Unlock()
+
+	files, err := db.fs.Files(db.path)
+	if err != nil {
+		return err
+	}
+
+	start := len(db.tail.files) - 1
+
+	if start < 0 || !db.tail.follows(files) {
+		db.kd = newKeyDir()
+		db.tail = tail{}
+		db.valueCRC = map[string]bool{}
+		db.versioned = false
+
+		start = 0
+	}
+
+	for i := start; i < len(files); i++ {
+		var offset uint64
+
+		if i < len(db.tail.files) {
+			offset = db.tail.offset
+		} else if i < len(files)-1 && db.loadHints(files[i]) {
+			continue
+		}
+
+		err := db.tailFile(files[i], offset)
+		if err != nil {
+			db.tail = tail{}
+
+			return err
+		}
+	}
+
+	db.tail = tail{
+		files:  files,
+		offset: db.kd.lastOffset,
+	}
+
+	return nil
+}
+
+// tailFile reads all fully written entries of the named data file starting at the given offset
+func (db *DB) tailFile(file string, offset uint64) error {
+	r := bufio.NewReader(&fileReader{
+		fs:     db.fs,
+		path:   db.path,
+		file:   file,
+		offset: int64(offset),
+	})
+
+	db.kd.lastOffset = offset
+
+	if offset == 0 {
+		fh, err := readFileHeader(r)
+		if err != nil {
+			// File header which is not fully written yet is read again upon the next refresh
+			if errors.Is(err, errEmptyFile) || errors.Is(err, errTornFileHeader) {
+				return nil
+			}
+
+			return err
+		}
+
+		db.kd.advanceOffsetBy(uint64(fh.size()))
+
+		err = db.setFormat(file, fh)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := db.readEntries(r, file)
+
+	// Entry which is not fully written yet is read again upon the next refresh
+	if errors.Is(err, io.EOF) || isTorn(err) {
+		return nil
+	}
+
+	return err
+}
+
+// fileReader reads a data file sequentially by means of FS.ReadFileAt
+type fileReader struct {
+	fs     FS
+	path   string
+	file   string
+	offset int64
+}
+
+func (r *fileReader) Read(p []byte) (int, error) {
+	n, err := r.fs.ReadFileAt(r.path, r.file, p, r.offset)
+
+	r.offset += int64(n)
+
+	if n > 0 && errors.Is(err, io.EOF) {
+		return n, nil
+	}
+
+	return n, err
+}
@@ -0,0 +1,113 @@
+package core_test
+
+import (
+	"fmt"
+	"github.com/aneshas/gocask/core"
+	"github.com/aneshas/gocask/core/testutil"
+	caskfs "github.com/aneshas/gocask/internal/fs"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"sort"
+	"testing"
+)
+
+func TestReadOnly_Should_Share_DB_With_Writer(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_ro")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	var time testutil.Time
+
+	writer, err := core.NewDB(dbPath, caskfs.NewDisk(), time, core.Config{MaxDataFileSize: 64})
+
+	assert.NoError(t, err)
+
+	defer writer.Close()
+
+	_, err = writer.Put([]byte("foo"), []byte("bar"))
+
+	assert.NoError(t, err)
+
+	reader, err := core.NewDB(dbPath, caskfs.NewDisk(), time, core.Config{ReadOnly: true})
+
+	assert.NoError(t, err)
+
+	got, err := reader.Get([]byte("foo"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("bar"), got)
+
+	for i := 0; i < 10; i++ {
+		_, err := writer.Put([]byte(fmt.Sprintf("key%d", i)), []byte("some value"))
+
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, writer.Delete([]byte("foo")))
+
+	_, err = reader.Get([]byte("key9"))
+
+	assert.ErrorIs(t, err, core.ErrKeyNotFound)
+
+	assert.NoError(t, reader.Refresh())
+
+	assertReaderKeys := func() {
+		keys := reader.Keys()
+
+		sort.Strings(keys)
+
+		assert.Equal(t, []string{"key0", "key1", "key2", "key3", "key4", "key5", "key6", "key7", "key8", "key9"}, keys)
+
+		got, err = reader.Get([]byte("key9"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("some value"), got)
+	}
+
+	assertReaderKeys()
+
+	assert.NoError(t, writer.Merge())
+	assert.NoError(t, reader.Refresh())
+
+	assertReaderKeys()
+}
+
+func TestReadOnly_Should_Reject_Writes(t *testing.T) {
+	var time testutil.Time
+
+	db, err := core.NewDB("", caskfs.NewInMemory(), time, core.Config{ReadOnly: true})
+
+	assert.NoError(t, err)
+
+	_, err = db.Put([]byte("foo"), []byte("bar"))
+
+	assert.ErrorIs(t, err, core.ErrReadOnly)
+	assert.ErrorIs(t, db.Delete([]byte("foo")), core.ErrReadOnly)
+	assert.ErrorIs(t, db.Merge(), core.ErrReadOnly)
+
+	var b core.Batch
+
+	b.Put([]byte("foo"), []byte("bar"))
+
+	assert.ErrorIs(t, db.Write(&b), core.ErrReadOnly)
+
+	assert.NoError(t, db.Close())
+}
+
+func TestReadOnly_Should_Release_Data_Files_Upon_Close(t *testing.T) {
+	var time testutil.Time
+
+	fs := testutil.NewFS()
+
+	fs.On("Files", fs.Path).Return([]string{}, nil)
+	fs.On("Close", fs.Path).Return(nil)
+
+	db, err := core.NewDB(fs.Path, fs, time, core.Config{ReadOnly: true})
+
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+
+	fs.AssertCalled(t, "Close", fs.Path)
+}
@@ -0,0 +1,203 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/aneshas/gocask/internal/crc"
+	"io"
+)
+
+var (
+	// ErrTornTail is thrown in strict mode upon opening a database whose data file ends with
+	// an incomplete or corrupted entry (eg. due to a crash during write)
+	ErrTornTail = errors.New("gocask: data file ends with a torn entry")
+
+	// errCorruptedTail signifies that the last entry of a data file failed the crc check
+	errCorruptedTail = fmt.Errorf("%w: last entry of the data file", ErrCRCFailed)
+)
+
+// Logger logs recovery actions taken upon startup
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// tornTailError signifies that the data file ends with an entry which was not completely written
+type tornTailError struct {
+	file   string
+	offset uint64
+	err    error
+}
+
+func (e *tornTailError) Error() string {
+	return fmt.Sprintf("data file %s at offset %d: %v", e.file, e.offset, e.err)
+}
+
+func (e *tornTailError) Unwrap() error {
+	return e.err
+}
+
+func isTorn(err error) bool {
+	return errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, errTornBatch) ||
+		errors.Is(err, errTornFileHeader) ||
+		errors.Is(err, errCorruptedTail)
+}
+
+// atTail reports whether the whole data file was read
+func atTail(r *bufio.Reader) bool {
+	_, err := r.Peek(1)
+
+	return errors.Is(err, io.EOF)
+}
+
+// discardWithCRC discards the next n bytes and returns the crc updated with them
+func discardWithCRC(r *bufio.Reader, sum uint32, n int) (uint32, error) {
+	for n > 0 {
+		chunk := r.Size()
+
+		if n < chunk {
+			chunk = n
+		}
+
+		b, err := r.Peek(chunk)
+
+		sum = crc.UpdateCRC32(sum, b)
+		n -= len(b)
+
+		_, _ = r.Discard(len(b))
+
+		if err != nil {
+			return 0, eofToUnexpected(err)
+		}
+	}
+
+	return sum, nil
+}
+
+// checkTornTail reports ErrCRCFailed if a valid entry follows the one at the offset which failed to be read.
+// Such entry was damaged (eg. its size) rather than not completely written, so it's not dropped
+// along with the entries which follow it. These can be salvaged with repair
+func (db *DB) checkTornTail(file string, offset uint64) error {
+	size, err := dataFileSize(db.fs, db.path, file)
+	if err != nil {
+		return err
+	}
+
+	s := scanner{
+		fs:       db.fs,
+		path:     db.path,
+		file:     file,
+		size:     size,
+		valueCRC: db.valueCRC[file],
+	}
+
+	// Entries of a torn batch are complete, but they do not follow it
+	n, torn, err := s.tornBatch(int64(offset))
+	if err != nil || torn {
+		return err
+	}
+
+	next, err := s.resync(int64(offset), n)
+	if err != nil {
+		return err
+	}
+
+	if next < 0 {
+		return nil
+	}
+
+	return fmt.Errorf(
+		"%w: damaged entry of %v at offset %d is followed by valid entries at offset %d (run gocask repair)",
+		ErrCRCFailed, file, offset, next,
+	)
+}
+
+// tornBatch reports whether the record at the offset is a batch which was not completely written.
+// Batch which exceeds the data file is still complete if its leading entries match its crc (its size was damaged),
+// in which case its actual size is returned
+func (s *scanner) tornBatch(offset int64) (int64, bool, error) {
+	b, err := s.peek(offset, int(headerSize+expirySize+seqSize))
+	if err != nil {
+		return 0, false, err
+	}
+
+	h, err := parseHeader(bytes.NewReader(b))
+	if err != nil || !h.Batch {
+		return 0, false, nil
+	}
+
+	start := offset + int64(h.size())
+	n := int64(h.ValueSize)
+
+	if start+n > s.size {
+		n = s.size - start
+	}
+
+	entries, err := s.peek(start, int(n))
+	if err != nil {
+		return 0, false, err
+	}
+
+	for pos := uint64(0); pos < uint64(len(entries)); {
+		eh, err := parseHeader(bytes.NewReader(entries[pos:]))
+		if err != nil {
+			break
+		}
+
+		pos += eh.entrySize()
+
+		if pos > uint64(len(entries)) {
+			break
+		}
+
+		complete := h
+
+		complete.ValueSize = uint32(pos)
+
+		if h.CRC == crc.UpdateCRC32(complete.keySum(nil), entries[:pos]) {
+			return int64(h.size()) + int64(pos), false, nil
+		}
+	}
+
+	return 0, true, nil
+}
+
+// truncateTornTail truncates the active data file back to the last good entry
+// so that new writes are not appended after the torn one
+func (db *DB) truncateTornTail(tt *tornTailError) error {
+	if db.cfg.Strict {
+		return fmt.Errorf("%w: %v", ErrTornTail, tt)
+	}
+
+	dropped := db.file.Size() - int64(tt.offset)
+
+	err := db.file.Truncate(int64(tt.offset))
+	if err != nil {
+		return fmt.Errorf("gocask: startup error: could not truncate torn tail of %v: %w", tt, err)
+	}
+
+	db.logf("gocask: truncated torn tail of %v (%d bytes dropped)", tt, dropped)
+
+	return nil
+}
+
+// skipTornTail ignores the torn tail of an immutable data file which can only be left behind by earlier versions
+func (db *DB) skipTornTail(tt *tornTailError) error {
+	if db.cfg.Strict {
+		return fmt.Errorf("%w: %v", ErrTornTail, tt)
+	}
+
+	db.logf("gocask: ignored torn tail of %v", tt)
+
+	return nil
+}
+
+func (db *DB) logf(format string, v ...interface{}) {
+	if db.cfg.Logger == nil {
+		return
+	}
+
+	db.cfg.Logger.Printf(format, v...)
+}
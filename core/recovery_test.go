@@ -0,0 +1,255 @@
+package core_test
+
+import (
+	"fmt"
+	"github.com/aneshas/gocask/core"
+	"github.com/aneshas/gocask/core/testutil"
+	caskfs "github.com/aneshas/gocask/internal/fs"
+	"github.com/stretchr/testify/assert"
+	"os"
+	gopath "path"
+	"testing"
+)
+
+func TestShould_Truncate_Torn_Tail_Of_Active_Data_File(t *testing.T) {
+	dbPath, file := writeTornTail(t, func(b []byte) []byte {
+		return b[:len(b)-3]
+	})
+
+	defer os.RemoveAll(dbPath)
+
+	before, err := os.Stat(file)
+
+	assert.NoError(t, err)
+
+	var logger recordingLogger
+
+	db := openDB(t, dbPath, caskfs.NewDisk(), testutil.Time(0), core.Config{MaxDataFileSize: 1024, Logger: &logger})
+
+	after, err := os.Stat(file)
+
+	assert.NoError(t, err)
+	assert.Less(t, after.Size(), before.Size())
+	assert.Len(t, logger.lines, 1)
+	assert.Contains(t, logger.lines[0], fmt.Sprintf("%d bytes dropped", before.Size()-after.Size()))
+
+	_, err = db.Put([]byte("baz"), []byte("baz"))
+
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+
+	db = openDB(t, dbPath, caskfs.NewDisk(), testutil.Time(0), core.Config{MaxDataFileSize: 1024, Logger: &logger})
+
+	defer db.Close()
+
+	assert.Len(t, logger.lines, 1)
+
+	assertRecovered(t, db, map[string]string{"foo": "foo", "baz": "baz"})
+}
+
+func TestShould_Truncate_Tail_Failing_CRC_Check(t *testing.T) {
+	dbPath, _ := writeTornTail(t, func(b []byte) []byte {
+		b[len(b)-1] ^= 0xff
+
+		return b
+	})
+
+	defer os.RemoveAll(dbPath)
+
+	var logger recordingLogger
+
+	db := openDB(t, dbPath, caskfs.NewDisk(), testutil.Time(0), core.Config{MaxDataFileSize: 1024, Logger: &logger})
+
+	defer db.Close()
+
+	assert.Len(t, logger.lines, 1)
+
+	assertRecovered(t, db, map[string]string{"foo": "foo"})
+}
+
+func TestShould_Refuse_To_Open_DB_With_Torn_Tail_In_Strict_Mode(t *testing.T) {
+	dbPath, file := writeTornTail(t, func(b []byte) []byte {
+		return b[:len(b)-3]
+	})
+
+	defer os.RemoveAll(dbPath)
+
+	before, err := os.ReadFile(file)
+
+	assert.NoError(t, err)
+
+	var time testutil.Time
+
+	_, err = core.NewDB(dbPath, caskfs.NewDisk(), time, core.Config{MaxDataFileSize: 1024, Strict: true})
+
+	assert.ErrorIs(t, err, core.ErrTornTail)
+
+	after, err := os.ReadFile(file)
+
+	assert.NoError(t, err)
+	assert.Equal(t, before, after)
+}
+
+func TestShould_Fail_Startup_On_Damaged_Entry_Size_Followed_By_Valid_Entries(t *testing.T) {
+	cases := []struct {
+		name        string
+		maxFileSize int64
+	}{
+		{name: "active data file", maxFileSize: 4096},
+		{name: "immutable data file", maxFileSize: 1024},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dbPath, err := os.MkdirTemp("", "gocask_recovery")
+
+			assert.NoError(t, err)
+
+			defer os.RemoveAll(dbPath)
+
+			var time testutil.Time
+
+			disk := caskfs.NewDisk()
+			cfg := core.Config{MaxDataFileSize: tc.maxFileSize}
+
+			db := openDB(t, dbPath, disk, time, cfg)
+
+			for i := 0; i < 100; i++ {
+				key := []byte(fmt.Sprintf("k%02d", i))
+
+				_, err = db.Put(key, key)
+
+				assert.NoError(t, err)
+			}
+
+			assert.NoError(t, db.Close())
+
+			files, _ := disk.Files(dbPath)
+			file := gopath.Join(dbPath, files[0]+".csk")
+
+			corrupt(t, file, func(b []byte) {
+				// Value size of the 11th entry (entries take 30 bytes following the file header)
+				b[24+10*30+15] ^= 0xff
+			})
+
+			before, err := os.ReadFile(file)
+
+			assert.NoError(t, err)
+
+			_, err = core.NewDB(dbPath, disk, time, cfg)
+
+			assert.ErrorIs(t, err, core.ErrCRCFailed)
+
+			after, err := os.ReadFile(file)
+
+			assert.NoError(t, err)
+			assert.Equal(t, before, after)
+
+			_, err = core.Repair(dbPath, disk, time, cfg)
+
+			assert.NoError(t, err)
+
+			db = openDB(t, dbPath, disk, time, cfg)
+
+			defer db.Close()
+
+			assert.Len(t, db.Keys(), 99)
+		})
+	}
+}
+
+func TestShould_Fail_Startup_On_Damaged_Batch_Size_Followed_By_Valid_Entries(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_recovery")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	var time testutil.Time
+
+	disk := caskfs.NewDisk()
+	cfg := core.Config{MaxDataFileSize: 1024}
+
+	db := openDB(t, dbPath, disk, time, cfg)
+
+	var b core.Batch
+
+	b.Put([]byte("foo"), []byte("foo"))
+	b.Put([]byte("bar"), []byte("bar"))
+
+	assert.NoError(t, db.Write(&b))
+
+	_, err = db.Put([]byte("baz"), []byte("baz"))
+
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+
+	files, _ := disk.Files(dbPath)
+
+	corrupt(t, gopath.Join(dbPath, files[0]+".csk"), func(b []byte) {
+		// Batch size exceeds the data file, though its entries are complete
+		b[24+15] ^= 0xff
+	})
+
+	_, err = core.NewDB(dbPath, disk, time, cfg)
+
+	assert.ErrorIs(t, err, core.ErrCRCFailed)
+}
+
+func writeTornTail(t *testing.T, tear func([]byte) []byte) (string, string) {
+	dbPath, err := os.MkdirTemp("", "gocask_recovery")
+
+	assert.NoError(t, err)
+
+	var time testutil.Time
+
+	disk := caskfs.NewDisk()
+
+	db, err := core.NewDB(dbPath, disk, time, core.Config{MaxDataFileSize: 1024})
+
+	assert.NoError(t, err)
+
+	_, err = db.Put([]byte("foo"), []byte("foo"))
+
+	assert.NoError(t, err)
+
+	_, err = db.Put([]byte("bar"), []byte("bar"))
+
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+
+	files, err := disk.Files(dbPath)
+
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	file := gopath.Join(dbPath, files[0]+".csk")
+
+	b, err := os.ReadFile(file)
+
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(file, tear(b), 0755))
+
+	return dbPath, file
+}
+
+func assertRecovered(t *testing.T, db *core.DB, want map[string]string) {
+	for key, val := range want {
+		got, err := db.Get([]byte(key))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(val), got)
+	}
+
+	_, err := db.Get([]byte("bar"))
+
+	assert.ErrorIs(t, err, core.ErrKeyNotFound)
+}
+
+type recordingLogger struct {
+	lines []string
+}
+
+func (l *recordingLogger) Printf(format string, v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
@@ -0,0 +1,206 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// errScrubStopped signifies that scrubbing was interrupted by closing the database
+var errScrubStopped = errors.New("gocask: scrubbing stopped")
+
+// ScrubPolicy configures background scrubbing, which periodically re-reads all immutable data files
+// and verifies their checksums so that corrupted data is discovered before it's read
+type ScrubPolicy struct {
+	// Interval between two consecutive scrubs of all immutable data files. Scrubbing is disabled if zero
+	Interval time.Duration
+
+	// BytesPerSecond throttles the reading of data files. Reading is not throttled if zero
+	BytesPerSecond int64
+
+	// OnCorruption is called (from the scrubbing goroutine) for every damaged data file found
+	OnCorruption func(FileReport)
+}
+
+func (p ScrubPolicy) validate() error {
+	if p.Interval < 0 || p.BytesPerSecond < 0 {
+		return fmt.Errorf("%w: scrub interval and rate should not be negative", ErrInvalidConfig)
+	}
+
+	return nil
+}
+
+// ScrubStats holds the results of background scrubbing
+type ScrubStats struct {
+	// Scrubs is the number of completed scrubs of all immutable data files
+	Scrubs int
+
+	// FilesScrubbed is the number of data files verified so far
+	FilesScrubbed int
+
+	// BytesScrubbed is the number of bytes read so far
+	BytesScrubbed int64
+
+	// LastScrub is the unix timestamp of the last completed scrub
+	LastScrub uint32
+
+	// Damaged holds the reports of damaged data files found by the last completed scrub
+	Damaged []FileReport
+}
+
+type scrubber struct {
+	m     sync.Mutex
+	stats ScrubStats
+}
+
+// ScrubStats returns the results of background scrubbing
+func (db *DB) ScrubStats() ScrubStats {
+	db.scrub.m.Lock()
+	defer db.scrub.m.Unlock()
+
+	stats := db.scrub.stats
+
+	stats.Damaged = append([]FileReport(nil), stats.Damaged...)
+
+	return stats
+}
+
+func (db *DB) startScrub() {
+	if db.cfg.Scrub.Interval == 0 {
+		return
+	}
+
+	db.goBackground(func(done <-chan struct{}) {
+		t := time.NewTicker(db.cfg.Scrub.Interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-t.C:
+				err := db.scrubFiles(done)
+				if errors.Is(err, errScrubStopped) {
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	})
+}
+
+func (db *DB) scrubFiles(done <-chan struct{}) error {
+	_, files, err := db.immutableFiles()
+	if err != nil {
+		return err
+	}
+
+	names, err := db.fs.Files(db.path)
+	if err != nil {
+		return err
+	}
+
+	th := throttle{
+		rate:  db.cfg.Scrub.BytesPerSecond,
+		start: time.Now(),
+		done:  done,
+	}
+
+	var damaged []FileReport
+
+	for _, name := range names {
+		if !files[name] {
+			continue
+		}
+
+		fr, err := db.scrubFile(name, &th)
+		if err != nil {
+			if errors.Is(err, errScrubStopped) {
+				return err
+			}
+
+			// There is nobody to report the error to and the data file may have been merged in the meantime
+			continue
+		}
+
+		if fr.Damaged() {
+			damaged = append(damaged, fr)
+
+			if db.cfg.Scrub.OnCorruption != nil {
+				db.cfg.Scrub.OnCorruption(fr)
+			}
+		}
+	}
+
+	db.scrub.m.Lock()
+	defer db.scrub.m.Unlock()
+
+	db.scrub.stats.Scrubs++
+	db.scrub.stats.LastScrub = db.time.NowUnix()
+	db.scrub.stats.Damaged = damaged
+
+	return nil
+}
+
+func (db *DB) scrubFile(file string, th *throttle) (FileReport, error) {
+	v := verifier{
+		fs:   db.fs,
+		path: db.path,
+		read: func(n int) error {
+			db.scrub.m.Lock()
+			db.scrub.stats.BytesScrubbed += int64(n)
+			db.scrub.m.Unlock()
+
+			return th.wait(n)
+		},
+	}
+
+	fr, err := v.verifyFile(file, func(_ header, _, _ []byte) error { return nil })
+	if err != nil {
+		return fr, err
+	}
+
+	db.scrub.m.Lock()
+	db.scrub.stats.FilesScrubbed++
+	db.scrub.m.Unlock()
+
+	return fr, nil
+}
+
+// throttle limits the rate at which data files are read
+type throttle struct {
+	rate  int64
+	start time.Time
+	n     int64
+	done  <-chan struct{}
+}
+
+// wait blocks until reading n more bytes does not exceed the rate
+func (t *throttle) wait(n int) error {
+	t.n += int64(n)
+
+	var ahead time.Duration
+
+	if t.rate > 0 {
+		ahead = time.Duration(float64(t.n)/float64(t.rate)*float64(time.Second)) - time.Since(t.start)
+	}
+
+	if ahead <= 0 {
+		select {
+		case <-t.done:
+			return errScrubStopped
+		default:
+			return nil
+		}
+	}
+
+	timer := time.NewTimer(ahead)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-t.done:
+		return errScrubStopped
+	}
+}
@@ -0,0 +1,113 @@
+package core_test
+
+import (
+	"github.com/aneshas/gocask/core"
+	"github.com/aneshas/gocask/core/testutil"
+	caskfs "github.com/aneshas/gocask/internal/fs"
+	"github.com/stretchr/testify/assert"
+	"os"
+	gopath "path"
+	"testing"
+	gotime "time"
+)
+
+func TestScrub_Should_Report_Corrupted_Immutable_Data_File(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_scrub")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	reports := make(chan core.FileReport, 10)
+
+	var time testutil.Time
+
+	disk := caskfs.NewDisk()
+
+	db, err := core.NewDB(dbPath, disk, time, core.Config{
+		MaxDataFileSize: 96,
+		Scrub: core.ScrubPolicy{
+			Interval: gotime.Millisecond,
+			OnCorruption: func(fr core.FileReport) {
+				reports <- fr
+			},
+		},
+	})
+
+	assert.NoError(t, err)
+
+	defer db.Close()
+
+	for _, key := range []string{"foo", "bar", "baz"} {
+		_, err := db.Put([]byte(key), []byte(key))
+
+		assert.NoError(t, err)
+	}
+
+	files, _ := disk.Files(dbPath)
+
+	assert.Len(t, files, 2)
+
+	corrupt(t, gopath.Join(dbPath, files[0]+".csk"), func(b []byte) {
+		// Value of the first entry
+		b[24+24+3] ^= 0xff
+	})
+
+	select {
+	case fr := <-reports:
+		assert.Equal(t, files[0], fr.File)
+		assert.Equal(t, 1, fr.CRCFailures)
+		assert.Equal(t, 1, fr.Entries)
+	case <-gotime.After(gotime.Second):
+		t.Fatal("corruption was not reported")
+	}
+
+	assert.Eventually(t, func() bool {
+		stats := db.ScrubStats()
+
+		return stats.Scrubs > 0 && len(stats.Damaged) == 1
+	}, gotime.Second, gotime.Millisecond)
+}
+
+func TestScrub_Should_Record_Stats_Of_Healthy_Data_Files(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_scrub")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	var time testutil.Time
+
+	db, err := core.NewDB(dbPath, caskfs.NewDisk(), time, core.Config{
+		MaxDataFileSize: 64,
+		Scrub: core.ScrubPolicy{
+			Interval:       gotime.Millisecond,
+			BytesPerSecond: 1024 * 1024,
+		},
+	})
+
+	assert.NoError(t, err)
+
+	for _, key := range []string{"foo", "bar", "baz"} {
+		_, err := db.Put([]byte(key), []byte(key))
+
+		assert.NoError(t, err)
+	}
+
+	assert.Eventually(t, func() bool {
+		stats := db.ScrubStats()
+
+		return stats.Scrubs > 1 && stats.FilesScrubbed > 0 && stats.BytesScrubbed > 0
+	}, gotime.Second, gotime.Millisecond)
+
+	assert.Empty(t, db.ScrubStats().Damaged)
+	assert.NoError(t, db.Close())
+}
+
+func TestScrub_Should_Reject_Negative_Interval(t *testing.T) {
+	var time testutil.Time
+
+	_, err := core.NewDB("", caskfs.NewInMemory(), time, core.Config{Scrub: core.ScrubPolicy{Interval: -1}})
+
+	assert.ErrorIs(t, err, core.ErrInvalidConfig)
+}
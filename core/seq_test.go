@@ -0,0 +1,162 @@
+package core_test
+
+import (
+	"github.com/aneshas/gocask/core"
+	"github.com/aneshas/gocask/core/testutil"
+	caskfs "github.com/aneshas/gocask/internal/fs"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSeq_Should_Be_Assigned_To_Every_Write_In_Order(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_seq")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	db := openDB(t, dbPath, caskfs.NewDisk(), testutil.Time(0), core.Config{MaxDataFileSize: 128})
+
+	seq, err := db.Put([]byte("foo"), []byte("foo"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), seq)
+
+	assert.NoError(t, db.Delete([]byte("foo")))
+
+	var b core.Batch
+
+	b.Put([]byte("bar"), []byte("bar"))
+	b.Put([]byte("baz"), []byte("baz"))
+
+	assert.NoError(t, db.Write(&b))
+
+	seq, err = db.PutIfAbsent([]byte("foo"), []byte("foo"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), seq)
+
+	// Failed conditional put does not take a sequence number
+	_, err = db.PutIfAbsent([]byte("foo"), []byte("foo"))
+
+	assert.ErrorIs(t, err, core.ErrConditionFailed)
+
+	_, version, err := db.GetWithVersion([]byte("baz"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), version)
+
+	assert.NoError(t, db.Close())
+
+	db = openDB(t, dbPath, caskfs.NewDisk(), testutil.Time(0), core.Config{MaxDataFileSize: 128})
+
+	defer db.Close()
+
+	_, version, err = db.GetWithVersion([]byte("foo"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), version)
+
+	seq, err = db.Put([]byte("foo"), []byte("foo"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(6), seq)
+}
+
+func TestSeq_Should_Not_Be_Reused_After_Merge_Drops_All_Entries(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_seq")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	db := openDB(t, dbPath, caskfs.NewDisk(), testutil.Time(0), core.Config{MaxDataFileSize: 128})
+
+	for i := 0; i < 3; i++ {
+		_, err = db.Put([]byte("foo"), []byte("foo"))
+
+		assert.NoError(t, err)
+	}
+
+	// Tombstone (sequence number 4) goes to the second data file
+	assert.NoError(t, db.Delete([]byte("foo")))
+
+	// Delete of a missing key which does not fit the second data file leaves an empty active data file behind
+	err = db.Delete([]byte(strings.Repeat("k", 64)))
+
+	assert.ErrorIs(t, err, core.ErrKeyNotFound)
+
+	assert.NoError(t, db.Merge())
+	assert.NoError(t, db.Close())
+
+	db = openDB(t, dbPath, caskfs.NewDisk(), testutil.Time(0), core.Config{MaxDataFileSize: 128})
+
+	defer db.Close()
+
+	assert.Empty(t, db.Keys())
+
+	seq, err := db.Put([]byte("bar"), []byte("bar"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), seq)
+}
+
+func TestSeq_Should_Be_Loaded_From_Hint_Files(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_seq")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	db := openDB(t, dbPath, caskfs.NewDisk(), testutil.Time(0), core.Config{MaxDataFileSize: 128})
+
+	want := map[string]uint64{}
+
+	for _, key := range []string{"foo", "bar", "baz", "qux"} {
+		seq, err := db.Put([]byte(key), []byte(key))
+
+		assert.NoError(t, err)
+
+		want[key] = seq
+	}
+
+	assert.NoError(t, db.Merge())
+	assert.NoError(t, db.Close())
+
+	db = openDB(t, dbPath, caskfs.NewDisk(), testutil.Time(0), core.Config{MaxDataFileSize: 128})
+
+	defer db.Close()
+
+	for key, seq := range want {
+		_, version, err := db.GetWithVersion([]byte(key))
+
+		assert.NoError(t, err)
+		assert.Equal(t, seq, version)
+	}
+}
+
+func TestSeq_Should_Report_Zero_Version_Of_Legacy_Entries(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_seq")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	writeFile(t, dbPath, "data_1_1.csk", legacyEntry("foo", "foo"))
+
+	db := openDB(t, dbPath, caskfs.NewDisk(), testutil.Time(0), core.Config{MaxDataFileSize: 128})
+
+	defer db.Close()
+
+	_, version, err := db.GetWithVersion([]byte("foo"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), version)
+
+	seq, err := db.PutIfVersion([]byte("foo"), []byte("bar"), 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), seq)
+}
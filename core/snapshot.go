@@ -0,0 +1,194 @@
+package core
+
+import (
+	"errors"
+)
+
+// ErrSnapshotReleased is thrown when reading from a snapshot which was already released
+var ErrSnapshotReleased = errors.New("gocask: snapshot is released")
+
+// Snapshot represents a read-only point-in-time view of the database.
+// Writes made after the snapshot was taken are not visible through it and
+// the data files it references are not removed by merge until it is released.
+// Key expiry is evaluated against the time the snapshot was taken.
+// Snapshot should be released as soon as it is no longer needed since
+// it retains the overwritten keydir entries as well as the merged data files
+type Snapshot struct {
+	db       *DB
+	kd       *keyDir
+	overlay  *kdOverlay
+	now      uint32
+	pins     []*retirement
+	released bool
+}
+
+// retirement holds data files which were merged while snapshots were open.
+// The files are removed once all of those snapshots are released
+type retirement struct {
+	files []string
+	snaps int
+}
+
+// Snapshot takes a snapshot of the current state of the database.
+// In read-only mode the data files can still be removed by the merge of the writing process
+func (db *DB) Snapshot() *Snapshot {
+	db.m.Lock()
+	defer db.m.Unlock()
+
+	s := Snapshot{
+		db:      db,
+		kd:      db.kd,
+		overlay: db.kd.newOverlay(),
+		now:     db.time.NowUnix(),
+	}
+
+	db.snaps[&s] = struct{}{}
+
+	return &s
+}
+
+// Get retrieves a value stored under given key at the time the snapshot was taken
+func (s *Snapshot) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, ErrInvalidKey
+	}
+
+	s.db.m.RLock()
+	defer s.db.m.RUnlock()
+
+	if s.released {
+		return nil, ErrSnapshotReleased
+	}
+
+	ke, err := s.kd.lookup(key, s.overlay)
+	if err != nil {
+		return nil, err
+	}
+
+	if ke.isExpired(s.now) {
+		return nil, ErrKeyNotFound
+	}
+
+	return s.db.readValue(key, ke)
+}
+
+// Iterator creates a new iterator over the snapshot which is positioned before the first key.
+// Snapshot keys are always visited in lexicographic order
+func (s *Snapshot) Iterator(opts ...IteratorOption) *Iterator {
+	return s.db.newIterator(s, opts...)
+}
+
+// Scan creates a new sorted iterator over snapshot keys starting with the given prefix
+func (s *Snapshot) Scan(prefix []byte) *Iterator {
+	return s.Range(prefix, prefixEnd(prefix))
+}
+
+// Range creates a new sorted iterator over snapshot keys in range [start, end).
+// Nil start or end leave the range unbounded on that side
+func (s *Snapshot) Range(start, end []byte) *Iterator {
+	return s.db.newIterator(s, inRange(start, end))
+}
+
+// Fold calls fn for every key/value pair in the snapshot until fn returns an error which is then returned by Fold
+func (s *Snapshot) Fold(fn func(key, val []byte) error, opts ...IteratorOption) error {
+	return fold(s.Iterator(opts...), fn)
+}
+
+// Release releases the snapshot along with the data files which were retained only for it.
+// Iterators created from the snapshot report ErrSnapshotReleased afterwards
+func (s *Snapshot) Release() error {
+	s.db.m.Lock()
+	defer s.db.m.Unlock()
+
+	if s.released {
+		return nil
+	}
+
+	s.released = true
+	s.kd.removeOverlay(s.overlay)
+	s.overlay = nil
+
+	delete(s.db.snaps, s)
+
+	var err error
+
+	for _, r := range s.pins {
+		r.snaps--
+
+		if r.snaps > 0 {
+			continue
+		}
+
+		rErr := s.db.removeRetired(r)
+		if err == nil {
+			err = rErr
+		}
+	}
+
+	s.pins = nil
+
+	return err
+}
+
+// retire removes the merged data files or retains them until open snapshots are released
+func (db *DB) retire(files []string) error {
+	if len(db.snaps) == 0 {
+		return db.removeFiles(files)
+	}
+
+	r := retirement{
+		files: files,
+		snaps: len(db.snaps),
+	}
+
+	for s := range db.snaps {
+		s.pins = append(s.pins, &r)
+	}
+
+	for _, file := range files {
+		db.retired[file] = &r
+	}
+
+	return nil
+}
+
+func (db *DB) removeRetired(r *retirement) error {
+	for _, file := range r.files {
+		delete(db.retired, file)
+	}
+
+	return db.removeFiles(r.files)
+}
+
+// removeAllRetired removes all retained data files regardless of open snapshots
+func (db *DB) removeAllRetired() error {
+	var err error
+
+	for _, r := range db.retired {
+		if r.snaps <= 0 {
+			continue
+		}
+
+		r.snaps = 0
+
+		rErr := db.removeRetired(r)
+		if err == nil {
+			err = rErr
+		}
+	}
+
+	return err
+}
+
+func (db *DB) removeFiles(files []string) error {
+	for _, file := range files {
+		err := db.fs.Remove(db.path, file)
+		if err != nil {
+			return err
+		}
+
+		delete(db.valueCRC, file)
+	}
+
+	return nil
+}
@@ -0,0 +1,322 @@
+package core_test
+
+import (
+	"fmt"
+	"github.com/aneshas/gocask/core"
+	"github.com/aneshas/gocask/core/testutil"
+	caskfs "github.com/aneshas/gocask/internal/fs"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+	gotime "time"
+)
+
+func TestSnapshot_Should_Not_See_Later_Writes(t *testing.T) {
+	db := getInMemDB(t)
+
+	defer db.Close()
+
+	_, _ = db.Put([]byte("foo"), []byte("foo val"))
+	_, _ = db.Put([]byte("bar"), []byte("bar val"))
+	_, _ = db.Put([]byte("baz"), []byte("baz val"))
+
+	snap := db.Snapshot()
+
+	defer snap.Release()
+
+	_, _ = db.Put([]byte("foo"), []byte("new foo val"))
+	_ = db.Delete([]byte("bar"))
+	_, _ = db.Put([]byte("new"), []byte("new val"))
+	_, _ = db.Put([]byte("foo"), []byte("newest foo val"))
+
+	got, err := snap.Get([]byte("foo"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("foo val"), got)
+
+	got, err = snap.Get([]byte("bar"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("bar val"), got)
+
+	_, err = snap.Get([]byte("new"))
+
+	assert.ErrorIs(t, err, core.ErrKeyNotFound)
+
+	got, err = db.Get([]byte("foo"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("newest foo val"), got)
+
+	_, err = db.Get([]byte("bar"))
+
+	assert.ErrorIs(t, err, core.ErrKeyNotFound)
+}
+
+func TestSnapshot_Should_Iterate_Over_Keys_At_Snapshot_Time(t *testing.T) {
+	db := getInMemDB(t)
+
+	defer db.Close()
+
+	for _, k := range []string{"user:1:name", "user:2:name", "user:3:name", "user:4:name"} {
+		_, _ = db.Put([]byte(k), []byte("old"))
+	}
+
+	snap := db.Snapshot()
+
+	defer snap.Release()
+
+	got := make(map[string]string)
+
+	it := snap.Scan([]byte("user:"))
+
+	for it.Next() {
+		got[string(it.Key())] = string(it.Value())
+
+		// Writes during iteration are not visible to the snapshot
+		_, _ = db.Put([]byte("user:0:name"), []byte("new"))
+		_, _ = db.Put([]byte("user:3:name"), []byte("new"))
+		_, _ = db.Put([]byte("user:5:name"), []byte("new"))
+		_ = db.Delete([]byte("user:4:name"))
+	}
+
+	assert.NoError(t, it.Err())
+	assert.NoError(t, it.Close())
+
+	assert.Equal(t, map[string]string{
+		"user:1:name": "old",
+		"user:2:name": "old",
+		"user:3:name": "old",
+		"user:4:name": "old",
+	}, got)
+
+	var keys []string
+
+	err := snap.Fold(func(key, val []byte) error {
+		keys = append(keys, string(key))
+
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user:1:name", "user:2:name", "user:3:name", "user:4:name"}, keys)
+}
+
+func TestSnapshot_Should_Iterate_Over_Keys_At_Snapshot_Time_Despite_Many_Later_Keys(t *testing.T) {
+	db := getInMemDB(t)
+
+	defer db.Close()
+
+	_, _ = db.Put([]byte("a"), []byte("old"))
+	_, _ = db.Put([]byte("z"), []byte("old"))
+
+	snap := db.Snapshot()
+
+	defer snap.Release()
+
+	// Enough keys for each keydir shard to hold more of them than is read ahead at once
+	for i := 0; i < 10000; i++ {
+		_, _ = db.Put([]byte(fmt.Sprintf("k%05d", i)), []byte("new"))
+	}
+
+	var keys []string
+
+	err := snap.Fold(func(key, val []byte) error {
+		keys = append(keys, string(key))
+
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "z"}, keys)
+}
+
+func TestSnapshot_Should_Evaluate_Expiry_At_Snapshot_Time(t *testing.T) {
+	clock := &testutil.Clock{Now: 1000}
+
+	db, _ := core.NewDB("", caskfs.NewInMemory(), clock, core.DefaultConfig)
+
+	defer db.Close()
+
+	_, _ = db.PutWithTTL([]byte("foo"), []byte("bar"), 10*gotime.Second)
+
+	snap := db.Snapshot()
+
+	defer snap.Release()
+
+	clock.Advance(10)
+
+	_, err := db.Get([]byte("foo"))
+
+	assert.ErrorIs(t, err, core.ErrKeyNotFound)
+
+	got, err := snap.Get([]byte("foo"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("bar"), got)
+}
+
+func TestSnapshot_Should_Report_Released_Snapshot(t *testing.T) {
+	db := getInMemDB(t)
+
+	defer db.Close()
+
+	_, _ = db.Put([]byte("foo"), []byte("bar"))
+	_, _ = db.Put([]byte("baz"), []byte("bar"))
+
+	snap := db.Snapshot()
+	it := snap.Iterator()
+
+	assert.True(t, it.Next())
+
+	assert.NoError(t, snap.Release())
+	assert.NoError(t, snap.Release())
+
+	_, err := snap.Get([]byte("foo"))
+
+	assert.ErrorIs(t, err, core.ErrSnapshotReleased)
+
+	assert.Nil(t, it.Value())
+	assert.False(t, it.Next())
+	assert.ErrorIs(t, it.Err(), core.ErrSnapshotReleased)
+}
+
+func TestSnapshot_Should_Retain_Merged_Data_Files_Until_Released(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_snapshot")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	var time testutil.Time
+
+	disk := caskfs.NewDisk()
+	cfg := core.Config{MaxDataFileSize: 64}
+
+	db, err := core.NewDB(dbPath, disk, time, cfg)
+
+	assert.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		_, err := db.Put([]byte(fmt.Sprintf("key%d", i)), []byte("old value"))
+
+		assert.NoError(t, err)
+	}
+
+	snap := db.Snapshot()
+
+	for i := 0; i < 10; i++ {
+		if i < 5 {
+			assert.NoError(t, db.Delete([]byte(fmt.Sprintf("key%d", i))))
+
+			continue
+		}
+
+		_, err := db.Put([]byte(fmt.Sprintf("key%d", i)), []byte("new value"))
+
+		assert.NoError(t, err)
+	}
+
+	filesBefore, _ := disk.Files(dbPath)
+
+	assert.NoError(t, db.Merge())
+
+	filesRetained, _ := disk.Files(dbPath)
+
+	assert.Greater(t, len(filesRetained), len(filesBefore))
+
+	assertSnapshot := func() {
+		for i := 0; i < 10; i++ {
+			got, err := snap.Get([]byte(fmt.Sprintf("key%d", i)))
+
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("old value"), got)
+		}
+	}
+
+	assertSnapshot()
+
+	// Retained files are not merged again
+	assert.NoError(t, db.Merge())
+
+	assertSnapshot()
+
+	assert.NoError(t, snap.Release())
+
+	filesAfter, _ := disk.Files(dbPath)
+
+	assert.Less(t, len(filesAfter), len(filesBefore))
+
+	assert.NoError(t, db.Close())
+
+	db, err = core.NewDB(dbPath, disk, time, cfg)
+
+	assert.NoError(t, err)
+
+	defer db.Close()
+
+	assert.Equal(t, []string{"key5", "key6", "key7", "key8", "key9"}, collectKeys(db))
+}
+
+func TestSnapshot_Should_Not_Bring_Back_Deleted_Keys_From_Retained_Files(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_snapshot")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	var time testutil.Time
+
+	disk := caskfs.NewDisk()
+	cfg := core.Config{MaxDataFileSize: 64}
+
+	db, err := core.NewDB(dbPath, disk, time, cfg)
+
+	assert.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		_, err := db.Put([]byte(fmt.Sprintf("key%d", i)), []byte("some value"))
+
+		assert.NoError(t, err)
+	}
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, db.Delete([]byte(fmt.Sprintf("key%d", i))))
+	}
+
+	_ = db.Snapshot()
+
+	// Enough writes for the tombstones to end up in immutable data files
+	for i := 0; i < 5; i++ {
+		_, err := db.Put([]byte("filler"), []byte("some value"))
+
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, db.Merge())
+
+	// Database is not closed properly so the retained files are left behind
+	assert.NoError(t, disk.Close(dbPath))
+
+	db, err = core.NewDB(dbPath, disk, time, cfg)
+
+	assert.NoError(t, err)
+
+	defer db.Close()
+
+	assert.Equal(t, []string{"filler", "key5", "key6", "key7", "key8", "key9"}, collectKeys(db))
+}
+
+func collectKeys(db *core.DB) []string {
+	var keys []string
+
+	it := db.Iterator(core.Sorted())
+
+	defer it.Close()
+
+	for it.Next() {
+		keys = append(keys, string(it.Key()))
+	}
+
+	return keys
+}
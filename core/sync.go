@@ -0,0 +1,114 @@
+package core
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrInvalidSyncPolicy is thrown when background sync policy is configured with a non-positive interval
+var ErrInvalidSyncPolicy = errors.New("gocask: sync interval should be positive")
+
+type syncMode int
+
+const (
+	syncNever syncMode = iota
+	syncAlways
+	syncInterval
+)
+
+// SyncPolicy determines when written data is flushed (fsynced) to durable storage
+type SyncPolicy struct {
+	mode     syncMode
+	interval time.Duration
+}
+
+var (
+	// SyncNever leaves flushing of written data up to the OS.
+	// This is the fastest option, but acknowledged writes can be lost upon an OS crash
+	SyncNever = SyncPolicy{mode: syncNever}
+
+	// SyncAlways flushes the active data file after every write before acknowledging it
+	SyncAlways = SyncPolicy{mode: syncAlways}
+)
+
+// SyncEvery flushes the active data file in the background at the given interval
+// which bounds the window of writes that can be lost upon an OS crash
+func SyncEvery(interval time.Duration) SyncPolicy {
+	return SyncPolicy{
+		mode:     syncInterval,
+		interval: interval,
+	}
+}
+
+func (p SyncPolicy) validate() error {
+	if p.mode == syncInterval && p.interval <= 0 {
+		return ErrInvalidSyncPolicy
+	}
+
+	return nil
+}
+
+// Sync flushes the active data file to durable storage
+func (db *DB) Sync() error {
+	if db.cfg.ReadOnly {
+		return nil
+	}
+
+	db.m.RLock()
+	defer db.m.RUnlock()
+
+	db.wm.Lock()
+	defer db.wm.Unlock()
+
+	return db.file.Sync()
+}
+
+func (db *DB) startSync() {
+	if db.cfg.SyncPolicy.mode != syncInterval {
+		return
+	}
+
+	db.goBackground(func(done <-chan struct{}) {
+		t := time.NewTicker(db.cfg.SyncPolicy.interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-t.C:
+				// There is nobody to report the error to, the next write or explicit Sync will retry
+				_ = db.Sync()
+			case <-done:
+				return
+			}
+		}
+	})
+}
+
+// goBackground runs fn in the background until the database is closed
+func (db *DB) goBackground(fn func(done <-chan struct{})) {
+	if db.done == nil {
+		db.done = make(chan struct{})
+	}
+
+	done := db.done
+
+	db.wg.Add(1)
+
+	go func() {
+		defer db.wg.Done()
+
+		fn(done)
+	}()
+}
+
+func (db *DB) stopBackground() {
+	if db.done == nil {
+		return
+	}
+
+	close(db.done)
+
+	db.wg.Wait()
+
+	db.done = nil
+}
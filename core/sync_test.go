@@ -0,0 +1,96 @@
+package core_test
+
+import (
+	"github.com/aneshas/gocask/core"
+	"github.com/aneshas/gocask/core/testutil"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	gotime "time"
+)
+
+func TestSyncAlways_Should_Sync_Every_Write(t *testing.T) {
+	fs := testutil.NewFS().WithMockWriteSupport()
+
+	var time testutil.Time
+
+	cfg := core.DefaultConfig
+
+	cfg.SyncPolicy = core.SyncAlways
+
+	db, err := core.NewDB(fs.Path, fs, time, cfg)
+
+	assert.NoError(t, err)
+
+	_, err = db.Put([]byte("foo"), []byte("bar"))
+
+	assert.NoError(t, err)
+
+	_, err = db.Put([]byte("baz"), []byte("bar"))
+
+	assert.NoError(t, err)
+
+	fs.VerifySynced(t, 2)
+}
+
+func TestSyncNever_Should_Not_Sync_Writes(t *testing.T) {
+	fs := testutil.NewFS().WithMockWriteSupport()
+
+	var time testutil.Time
+
+	db, err := core.NewDB(fs.Path, fs, time, core.DefaultConfig)
+
+	assert.NoError(t, err)
+
+	_, err = db.Put([]byte("foo"), []byte("bar"))
+
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+
+	fs.VerifySynced(t, 0)
+}
+
+func TestSyncEvery_Should_Sync_In_The_Background(t *testing.T) {
+	fs := testutil.NewFS().WithMockWriteSupport()
+
+	var time testutil.Time
+
+	cfg := core.DefaultConfig
+
+	cfg.SyncPolicy = core.SyncEvery(gotime.Millisecond)
+
+	db, err := core.NewDB(fs.Path, fs, time, cfg)
+
+	assert.NoError(t, err)
+
+	_, err = db.Put([]byte("foo"), []byte("bar"))
+
+	assert.NoError(t, err)
+
+	assert.Eventually(t, fs.Synced, gotime.Second, gotime.Millisecond)
+
+	assert.NoError(t, db.Close())
+}
+
+func TestShould_Sync_Explicitly(t *testing.T) {
+	fs := testutil.NewFS().WithMockWriteSupport()
+
+	var time testutil.Time
+
+	db, _ := core.NewDB(fs.Path, fs, time, core.DefaultConfig)
+
+	assert.NoError(t, db.Sync())
+
+	fs.VerifySynced(t, 1)
+}
+
+func TestShould_Validate_Sync_Interval(t *testing.T) {
+	var time testutil.Time
+
+	cfg := core.DefaultConfig
+
+	cfg.SyncPolicy = core.SyncEvery(0)
+
+	_, err := core.NewDB("", testutil.NewFS(), time, cfg)
+
+	assert.ErrorIs(t, err, core.ErrInvalidSyncPolicy)
+}
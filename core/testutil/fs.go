@@ -6,6 +6,7 @@ import (
 	"github.com/aneshas/gocask/core/testutil/mocks"
 	"github.com/stretchr/testify/mock"
 	"io"
+	"os"
 	"testing"
 )
 
@@ -56,8 +57,10 @@ func (fs *FS) WithMockWriteSupport() *FS {
 	file.On("Write", mock.Anything).Return(0, nil)
 	file.On("Close").Return(nil)
 	file.On("Size").Return(int64(0))
+	file.On("Sync").Return(nil)
 
 	fs.On("Open", fs.Path).Return(&file, nil)
+	fs.On("Close", fs.Path).Return(nil)
 	fs.On("Walk", fs.Path, mock.Anything).Return(nil)
 
 	fs.file = &file
@@ -65,6 +68,22 @@ func (fs *FS) WithMockWriteSupport() *FS {
 	return fs
 }
 
+// VerifySynced verifies that active data file was synced n times
+func (fs *FS) VerifySynced(t *testing.T, n int) {
+	fs.file.AssertNumberOfCalls(t, "Sync", n)
+}
+
+// Synced reports whether active data file was synced at least once
+func (fs *FS) Synced() bool {
+	return fs.file.AssertCalled(discardT{}, "Sync")
+}
+
+type discardT struct{}
+
+func (discardT) Logf(string, ...interface{})   {}
+func (discardT) Errorf(string, ...interface{}) {}
+func (discardT) FailNow()                      {}
+
 // WithToppedUpDataFile setup
 func (fs *FS) WithToppedUpDataFile(atSize int64) *FS {
 	var file mocks.File
@@ -74,6 +93,7 @@ func (fs *FS) WithToppedUpDataFile(atSize int64) *FS {
 	file.On("Size").Return(atSize)
 
 	fs.On("Open", fs.Path).Return(&file, nil)
+	fs.On("Close", fs.Path).Return(nil)
 	fs.On("Walk", fs.Path, mock.Anything).Return(nil)
 
 	var newFile mocks.File
@@ -111,6 +131,7 @@ func (fs *FS) WithFailWithErrOnWrite(err error) *FS {
 	file.On("Size").Return(int64(0))
 
 	fs.On("Open", fs.Path).Return(&file, nil)
+	fs.On("Close", fs.Path).Return(nil)
 	fs.On("Walk", fs.Path, mock.Anything).Return(nil)
 
 	fs.file = &file
@@ -161,6 +182,11 @@ func (fs *FS) UseMockDataFiles() *FS {
 	file.On("Close").Return(nil)
 
 	fs.On("Open", fs.Path).Return(&file, nil)
+	fs.On("Close", fs.Path).Return(nil)
+	fs.On("OpenHintFile", fs.Path, mock.Anything).Return(nil, os.ErrNotExist)
+
+	// Mock data files are written in the legacy format so the active one gets rotated upon startup
+	fs.On("Rotate", fs.Path).Return(&echoFile{name: "new-data-file"}, nil)
 
 	fs.On("ReadFileAt", fs.Path, mock.Anything, mock.Anything, mock.Anything).
 		Run(func(args mock.Arguments) {
@@ -224,3 +250,11 @@ func (e *echoFile) Name() string {
 func (e *echoFile) Size() int64 {
 	return 0
 }
+
+func (e *echoFile) Sync() error {
+	return nil
+}
+
+func (e *echoFile) Truncate(_ int64) error {
+	return nil
+}
@@ -16,6 +16,10 @@ func (i *InMemoryFile) Read(p []byte) (n int, err error) {
 }
 
 func (i *InMemoryFile) Write(p []byte) (int, error) {
+	if i.fs.writeHook != nil {
+		i.fs.writeHook(p)
+	}
+
 	if i.fs.pwKey != nil && bytes.Contains(p, i.fs.pwKey) {
 
 		l := len(p) - 1
@@ -40,9 +44,18 @@ func (i *InMemoryFile) Size() int64 {
 	return 0
 }
 
+func (i *InMemoryFile) Sync() error {
+	return i.file.Sync()
+}
+
+func (i *InMemoryFile) Truncate(size int64) error {
+	return i.file.Truncate(size)
+}
+
 type InMemory struct {
-	fs    core.FS
-	pwKey []byte
+	fs        core.FS
+	pwKey     []byte
+	writeHook func([]byte)
 }
 
 func NewInMemory(fs core.FS) *InMemory {
@@ -63,6 +76,10 @@ func (i *InMemory) Open(path string) (core.File, error) {
 	}, nil
 }
 
+func (i *InMemory) Close(path string) error {
+	return i.fs.Close(path)
+}
+
 func (i *InMemory) Rotate(path string) (core.File, error) {
 	return i.Rotate(path)
 }
@@ -80,3 +97,34 @@ func (i *InMemory) WithPartialWriteFor(key []byte) *InMemory {
 
 	return i
 }
+
+// WithWriteHook calls the hook with every chunk written to a data file before it's written
+func (i *InMemory) WithWriteHook(hook func([]byte)) *InMemory {
+	i.writeHook = hook
+
+	return i
+}
+
+func (i *InMemory) Files(path string) ([]string, error) {
+	return i.fs.Files(path)
+}
+
+func (i *InMemory) CreateMergeFile(path string, active string) (core.File, error) {
+	return i.fs.CreateMergeFile(path, active)
+}
+
+func (i *InMemory) CommitMerge(path string, files []string) error {
+	return i.fs.CommitMerge(path, files)
+}
+
+func (i *InMemory) Remove(path string, file string) error {
+	return i.fs.Remove(path, file)
+}
+
+func (i *InMemory) CreateHintFile(path string, dataFile string) (core.File, error) {
+	return i.fs.CreateHintFile(path, dataFile)
+}
+
+func (i *InMemory) OpenHintFile(path string, dataFile string) (core.File, error) {
+	return i.fs.OpenHintFile(path, dataFile)
+}
@@ -72,6 +72,34 @@ func (_m *File) Size() int64 {
 	return r0
 }
 
+// Sync provides a mock function with given fields:
+func (_m *File) Sync() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Truncate provides a mock function with given fields: _a0
+func (_m *File) Truncate(_a0 int64) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int64) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Write provides a mock function with given fields: p
 func (_m *File) Write(p []byte) (int, error) {
 	ret := _m.Called(p)
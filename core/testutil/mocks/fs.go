@@ -12,6 +12,103 @@ type FS struct {
 	mock.Mock
 }
 
+// Close provides a mock function with given fields: _a0
+func (_m *FS) Close(_a0 string) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CommitMerge provides a mock function with given fields: _a0, _a1
+func (_m *FS) CommitMerge(_a0 string, _a1 []string) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, []string) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateHintFile provides a mock function with given fields: _a0, _a1
+func (_m *FS) CreateHintFile(_a0 string, _a1 string) (core.File, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 core.File
+	if rf, ok := ret.Get(0).(func(string, string) core.File); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(core.File)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateMergeFile provides a mock function with given fields: _a0, _a1
+func (_m *FS) CreateMergeFile(_a0 string, _a1 string) (core.File, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 core.File
+	if rf, ok := ret.Get(0).(func(string, string) core.File); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(core.File)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Files provides a mock function with given fields: _a0
+func (_m *FS) Files(_a0 string) ([]string, error) {
+	ret := _m.Called(_a0)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(string) []string); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Open provides a mock function with given fields: _a0
 func (_m *FS) Open(_a0 string) (core.File, error) {
 	ret := _m.Called(_a0)
@@ -35,6 +132,29 @@ func (_m *FS) Open(_a0 string) (core.File, error) {
 	return r0, r1
 }
 
+// OpenHintFile provides a mock function with given fields: _a0, _a1
+func (_m *FS) OpenHintFile(_a0 string, _a1 string) (core.File, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 core.File
+	if rf, ok := ret.Get(0).(func(string, string) core.File); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(core.File)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ReadFileAt provides a mock function with given fields: _a0, _a1, _a2, _a3
 func (_m *FS) ReadFileAt(_a0 string, _a1 string, _a2 []byte, _a3 int64) (int, error) {
 	ret := _m.Called(_a0, _a1, _a2, _a3)
@@ -56,6 +176,20 @@ func (_m *FS) ReadFileAt(_a0 string, _a1 string, _a2 []byte, _a3 int64) (int, er
 	return r0, r1
 }
 
+// Remove provides a mock function with given fields: _a0, _a1
+func (_m *FS) Remove(_a0 string, _a1 string) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Rotate provides a mock function with given fields: _a0
 func (_m *FS) Rotate(_a0 string) (core.File, error) {
 	ret := _m.Called(_a0)
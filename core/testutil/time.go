@@ -9,3 +9,18 @@ func (t Time) NowUnix() uint32 {
 
 	return uint32(t)
 }
+
+// Clock is a time provider which can be moved forward
+type Clock struct {
+	Now uint32
+}
+
+// NowUnix returns current clock time
+func (c *Clock) NowUnix() uint32 {
+	return c.Now
+}
+
+// Advance moves the clock forward by the given number of seconds
+func (c *Clock) Advance(seconds uint32) {
+	c.Now += seconds
+}
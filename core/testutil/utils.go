@@ -7,7 +7,23 @@ import (
 
 var bo binary.ByteOrder = binary.LittleEndian
 
-func Entry(now uint32, key, val []byte) []byte {
+// Entry serializes the entry carrying the sequence number with crc covering the header fields, key and value
+func Entry(now uint32, seq uint64, key, val []byte) []byte {
+	b := AppendBytes(
+		U32ToB(now),
+		U32ToB(uint32(len(key))|1<<29),
+		U32ToB(uint32(len(val))),
+		U64ToB(seq),
+		key,
+		val,
+	)
+
+	return AppendBytes(U32ToB(crc.CalcCRC32(b)), b)
+}
+
+// LegacyEntry serializes the entry with crc of the value only, as found in data files
+// written in the older format (eg. mock data files which do not start with a file header)
+func LegacyEntry(now uint32, key, val []byte) []byte {
 	return AppendBytes(
 		U32ToB(crc.CalcCRC32(val)),
 		U32ToB(now),
@@ -26,6 +42,14 @@ func U32ToB(i uint32) []byte {
 	return b
 }
 
+func U64ToB(i uint64) []byte {
+	b := make([]byte, 8)
+
+	bo.PutUint64(b, i)
+
+	return b
+}
+
 func AppendBytes(chunks ...[]byte) []byte {
 	var b []byte
 
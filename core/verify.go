@@ -0,0 +1,572 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+)
+
+// errInvalidEntry signifies bytes which can not be an entry (eg. sizes out of bounds or empty key)
+var errInvalidEntry = errors.New("gocask: invalid entry")
+
+// scanWindow is the number of bytes read from the data file at once while scanning
+const scanWindow = 1 << 20
+
+// FileReport summarizes the integrity of a single data file
+type FileReport struct {
+	File string
+
+	// Entries is the number of valid entries (entries of a batch are counted one by one)
+	Entries int
+
+	// CRCFailures is the number of damaged regions which are followed by valid entries
+	CRCFailures int
+
+	// TornRecords is the number of damaged regions at the end of the data file
+	// (eg. an entry which was not completely written)
+	TornRecords int
+
+	// OrphanedTombstones is the number of tombstones of keys which are not stored by the preceding entries
+	OrphanedTombstones int
+
+	// DamagedBytes is the number of bytes skipped over while resynchronising past damaged regions
+	DamagedBytes int64
+}
+
+// Damaged reports whether any part of the data file could not be read
+func (fr FileReport) Damaged() bool {
+	return fr.CRCFailures > 0 || fr.TornRecords > 0
+}
+
+// Report summarizes the integrity of all data files of the database
+type Report struct {
+	Files []FileReport
+}
+
+// Damaged reports whether any of the data files is damaged
+func (r *Report) Damaged() bool {
+	for _, fr := range r.Files {
+		if fr.Damaged() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Verify scans every data file of the database and reports its integrity. Damaged regions are skipped
+// by resynchronising at the next valid entry. The database is not locked, so it can be verified
+// while it's in use, in which case the entry being written may be reported as torn
+func Verify(dbpath string, fs FS, cfg Config) (*Report, error) {
+	v := newVerifier(fs, path.Join(cfg.DataDir, dbpath))
+
+	files, err := fs.Files(v.path)
+	if err != nil {
+		return nil, err
+	}
+
+	err = v.verify(files, func(_ header, _, _ []byte) error { return nil })
+	if err != nil {
+		return nil, err
+	}
+
+	return &v.report, nil
+}
+
+// Repair salvages all valid entries of a database into a fresh set of data files (written in the current format)
+// and removes the original data files. Damaged regions are skipped by resynchronising at the next valid entry.
+// The database is locked for the duration of the repair
+func Repair(dbpath string, fs FS, time Time, cfg Config) (*Report, error) {
+	err := cfg.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	dbpath = path.Join(cfg.DataDir, dbpath)
+
+	active, err := fs.Open(dbpath)
+	if err != nil {
+		return nil, err
+	}
+
+	defer fs.Close(dbpath)
+
+	err = active.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := fs.Files(dbpath)
+	if err != nil {
+		return nil, err
+	}
+
+	v := newVerifier(fs, dbpath)
+
+	w := repairWriter{
+		fs:   fs,
+		path: dbpath,
+		time: time,
+		cfg:  cfg,
+		seq:  lastFileSeq(fs, dbpath, files),
+	}
+
+	err = v.verify(files, w.write)
+	if err != nil {
+		_ = w.close()
+
+		return nil, fmt.Errorf("gocask: repair error: %w", err)
+	}
+
+	// Original data files are removed only once all salvaged entries are durable. Should the repair be interrupted,
+	// salvaged entries are applied once again after the original ones upon startup
+	err = w.close()
+	if err != nil {
+		return nil, fmt.Errorf("gocask: repair error: %w", err)
+	}
+
+	for _, file := range files {
+		err = fs.Remove(dbpath, file)
+		if err != nil {
+			return nil, fmt.Errorf("gocask: repair error: %w", err)
+		}
+	}
+
+	return &v.report, nil
+}
+
+type verifier struct {
+	fs     FS
+	path   string
+	report Report
+
+	// live keys are tracked in order to find orphaned tombstones (if not nil)
+	live map[string]struct{}
+
+	// read is called after each read from the data file (if not nil)
+	read func(n int) error
+}
+
+func newVerifier(fs FS, dbpath string) *verifier {
+	return &verifier{
+		fs:   fs,
+		path: dbpath,
+		live: map[string]struct{}{},
+	}
+}
+
+// verify scans the data files in order and passes each valid entry (entries of a batch one by one) to fn
+func (v *verifier) verify(files []string, fn func(h header, key, val []byte) error) error {
+	for _, file := range files {
+		fr, err := v.verifyFile(file, fn)
+		if err != nil {
+			return fmt.Errorf("data file %s: %w", file, err)
+		}
+
+		v.report.Files = append(v.report.Files, fr)
+	}
+
+	return nil
+}
+
+func (v *verifier) verifyFile(file string, fn func(h header, key, val []byte) error) (FileReport, error) {
+	fr := FileReport{File: file}
+
+	fh, err := readFileHeader(bufio.NewReader(&fileReader{
+		fs:   v.fs,
+		path: v.path,
+		file: file,
+	}))
+	if err != nil {
+		if errors.Is(err, errEmptyFile) {
+			return fr, nil
+		}
+
+		if errors.Is(err, errTornFileHeader) {
+			fr.TornRecords++
+
+			return fr, nil
+		}
+
+		return fr, err
+	}
+
+	size, err := dataFileSize(v.fs, v.path, file)
+	if err != nil {
+		return fr, err
+	}
+
+	s := scanner{
+		fs:       v.fs,
+		path:     v.path,
+		file:     file,
+		size:     size,
+		valueCRC: fh.valueCRC(),
+		read:     v.read,
+	}
+
+	offset := int64(fh.size())
+
+	for {
+		e, n, err := s.entryAt(offset)
+		if errors.Is(err, io.EOF) {
+			return fr, nil
+		}
+
+		if err == nil {
+			err = v.apply(&fr, e, fn)
+			if err != nil {
+				return fr, err
+			}
+
+			offset += n
+
+			continue
+		}
+
+		if !isDamaged(err) {
+			return fr, err
+		}
+
+		next, err := s.resync(offset, n)
+		if err != nil {
+			return fr, err
+		}
+
+		if next < 0 {
+			// Nothing valid follows the damaged region
+			fr.TornRecords++
+			fr.DamagedBytes += s.size - offset
+
+			return fr, nil
+		}
+
+		fr.CRCFailures++
+		fr.DamagedBytes += next - offset
+
+		offset = next
+	}
+}
+
+func (v *verifier) apply(fr *FileReport, entries []entry, fn func(h header, key, val []byte) error) error {
+	for _, e := range entries {
+		v.count(fr, e)
+
+		err := fn(e.h, e.key, e.val)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (v *verifier) count(fr *FileReport, e entry) {
+	fr.Entries++
+
+	if v.live == nil {
+		return
+	}
+
+	if !e.h.isTombstone() {
+		v.live[string(e.key)] = struct{}{}
+
+		return
+	}
+
+	// Tombstone key is stored in place of the value
+	if _, ok := v.live[string(e.val)]; !ok {
+		fr.OrphanedTombstones++
+	}
+
+	delete(v.live, string(e.val))
+}
+
+func isDamaged(err error) bool {
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, ErrCRCFailed) || errors.Is(err, errInvalidEntry)
+}
+
+// scanner reads a data file by means of FS.ReadFileAt so that it can go back
+// in order to resynchronise past a damaged region
+type scanner struct {
+	fs         FS
+	path, file string
+	valueCRC   bool
+	read       func(n int) error
+
+	buf  []byte
+	base int64
+	size int64
+}
+
+// peek returns n bytes at the given offset or less if the data file ends sooner
+func (s *scanner) peek(offset int64, n int) ([]byte, error) {
+	if offset < s.base || offset > s.base+int64(len(s.buf)) {
+		s.base = offset
+		s.buf = s.buf[:0]
+	}
+
+	// Bytes before the offset are no longer needed
+	if skip := offset - s.base; skip > scanWindow {
+		s.buf = append(s.buf[:0], s.buf[skip:]...)
+		s.base = offset
+	}
+
+	start := int(offset - s.base)
+
+	for len(s.buf) < start+n && s.base+int64(len(s.buf)) < s.size {
+		chunk := make([]byte, scanWindow)
+
+		m, err := s.fs.ReadFileAt(s.path, s.file, chunk, s.base+int64(len(s.buf)))
+
+		s.buf = append(s.buf, chunk[:m]...)
+
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+
+		if s.read != nil {
+			err = s.read(m)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if m == 0 {
+			break
+		}
+	}
+
+	end := start + n
+
+	if end > len(s.buf) {
+		end = len(s.buf)
+	}
+
+	return s.buf[start:end], nil
+}
+
+// entryAt reads the entry at the given offset (or the entries of the batch) and returns it along with its size.
+// The size is returned even if the entry is corrupted, so that the following entry can be looked for
+func (s *scanner) entryAt(offset int64) ([]entry, int64, error) {
+	b, err := s.peek(offset, int(headerSize+expirySize+seqSize))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(b) == 0 {
+		return nil, 0, io.EOF
+	}
+
+	h, err := parseHeader(bytes.NewReader(b))
+	if err != nil {
+		return nil, 0, eofToUnexpected(err)
+	}
+
+	n := h.entrySize()
+
+	// Sizes are checked before the entry is read, since they may be corrupted
+	if uint64(offset)+n > uint64(s.size) {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+
+	// Keys (stored in place of the value for tombstones) and batches can not be empty
+	if h.KeySize == 0 && h.ValueSize == 0 || h.Batch && h.KeySize != 0 {
+		return nil, int64(n), errInvalidEntry
+	}
+
+	b, err = s.peek(offset, int(n))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	er := entryReader{
+		r:        bufio.NewReader(bytes.NewReader(b)),
+		offset:   uint64(offset),
+		valueCRC: s.valueCRC,
+		values:   true,
+		bounded:  true,
+	}
+
+	var entries []entry
+
+	for {
+		e, err := er.next()
+		if errors.Is(err, io.EOF) {
+			return entries, int64(n), nil
+		}
+
+		if err != nil {
+			return nil, int64(n), err
+		}
+
+		entries = append(entries, e)
+	}
+}
+
+// resync looks for the first valid entry following the damaged entry of the given size at the offset.
+// Negative offset is returned if there is none
+func (s *scanner) resync(offset, size int64) (int64, error) {
+	// Most likely only the value of the entry is corrupted
+	if size > 0 {
+		_, _, err := s.entryAt(offset + size)
+		if err == nil {
+			return offset + size, nil
+		}
+	}
+
+	for next := offset + 1; next < s.size; next++ {
+		_, _, err := s.entryAt(next)
+		if err == nil {
+			return next, nil
+		}
+
+		if !isDamaged(err) {
+			return 0, err
+		}
+	}
+
+	return -1, nil
+}
+
+// dataFileSize finds the size of the named data file by probing it with FS.ReadFileAt
+func dataFileSize(fs FS, path, file string) (int64, error) {
+	b := make([]byte, 1)
+
+	readable := func(offset int64) (bool, error) {
+		n, err := fs.ReadFileAt(path, file, b, offset)
+		if n == 1 {
+			return true, nil
+		}
+
+		if err == nil || errors.Is(err, io.EOF) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	lo, hi := int64(0), int64(1)
+
+	for {
+		ok, err := readable(hi - 1)
+		if err != nil {
+			return 0, err
+		}
+
+		if !ok {
+			break
+		}
+
+		lo, hi = hi, hi*2
+	}
+
+	// Size is the first offset which can not be read
+	hi--
+
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+
+		ok, err := readable(mid)
+		if err != nil {
+			return 0, err
+		}
+
+		if ok {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+
+	return lo, nil
+}
+
+// lastFileSeq returns the largest sequence number recorded in the file headers of the data files.
+// Data files whose header can not be read are skipped
+func lastFileSeq(fs FS, path string, files []string) uint64 {
+	var seq uint64
+
+	for _, file := range files {
+		fh, err := readFileHeader(bufio.NewReader(&fileReader{
+			fs:   fs,
+			path: path,
+			file: file,
+		}))
+		if err == nil && fh.Seq > seq {
+			seq = fh.Seq
+		}
+	}
+
+	return seq
+}
+
+// repairWriter writes the salvaged entries into fresh data files
+type repairWriter struct {
+	fs   FS
+	path string
+	time Time
+	cfg  Config
+	file File
+
+	// seq is the last sequence number of the original data files (or the salvaged entries written so far)
+	seq uint64
+}
+
+func (w *repairWriter) write(h header, key, val []byte) error {
+	// Entries of data files written in the older format are checked by value only
+	h.CRC = h.checksum(key, val)
+
+	if h.Seq > w.seq {
+		w.seq = h.Seq
+	}
+
+	err := w.rotate(int64(h.entrySize()))
+	if err != nil {
+		return err
+	}
+
+	_, err = w.file.Write(serializeEntry(h, key, val))
+
+	return err
+}
+
+func (w *repairWriter) rotate(entrySz int64) error {
+	if w.file != nil && (w.file.Size()+entrySz) <= w.cfg.MaxDataFileSize {
+		return nil
+	}
+
+	err := w.close()
+	if err != nil {
+		return err
+	}
+
+	w.file, err = w.fs.Rotate(w.path)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.file.Write(newFileHeader(w.time.NowUnix(), w.seq).encode())
+
+	return err
+}
+
+func (w *repairWriter) close() error {
+	if w.file == nil {
+		return nil
+	}
+
+	err := w.file.Sync()
+	if err == nil {
+		err = w.file.Close()
+	} else {
+		_ = w.file.Close()
+	}
+
+	w.file = nil
+
+	return err
+}
@@ -0,0 +1,185 @@
+package core_test
+
+import (
+	"github.com/aneshas/gocask/core"
+	"github.com/aneshas/gocask/core/testutil"
+	caskfs "github.com/aneshas/gocask/internal/fs"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestVerify_Should_Report_Healthy_DB(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_verify")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	var time testutil.Time
+
+	db, err := core.NewDB(dbPath, caskfs.NewDisk(), time, core.Config{MaxDataFileSize: 1024})
+
+	assert.NoError(t, err)
+
+	_, err = db.Put([]byte("foo"), []byte("foo"))
+
+	assert.NoError(t, err)
+	assert.NoError(t, db.Delete([]byte("foo")))
+
+	var b core.Batch
+
+	b.Put([]byte("bar"), []byte("bar"))
+	b.Put([]byte("baz"), []byte("baz"))
+
+	assert.NoError(t, db.Write(&b))
+	assert.NoError(t, db.Close())
+
+	report, err := core.Verify(dbPath, caskfs.NewDisk(), core.Config{})
+
+	assert.NoError(t, err)
+	assert.False(t, report.Damaged())
+	assert.Len(t, report.Files, 1)
+	assert.Equal(t, 4, report.Files[0].Entries)
+}
+
+func TestVerify_Should_Report_Orphaned_Tombstones(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_verify")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	writeFile(t, dbPath, "data_1_1.csk", legacyEntry("foo", "foo"), legacyEntry("", "foo"), legacyEntry("", "bar"))
+
+	report, err := core.Verify(dbPath, caskfs.NewDisk(), core.Config{})
+
+	assert.NoError(t, err)
+	assert.False(t, report.Damaged())
+	assert.Equal(t, core.FileReport{File: "data_1_1", Entries: 3, OrphanedTombstones: 1}, report.Files[0])
+}
+
+func TestVerify_Should_Resync_Past_Corrupted_Value(t *testing.T) {
+	dbPath, file := writeEntries(t, "foo", "bar", "baz")
+
+	defer os.RemoveAll(dbPath)
+
+	corrupt(t, file, func(b []byte) {
+		// Value of the second entry
+		b[24+30+24+3] ^= 0xff
+	})
+
+	report, err := core.Verify(dbPath, caskfs.NewDisk(), core.Config{})
+
+	assert.NoError(t, err)
+	assert.True(t, report.Damaged())
+	assert.Equal(t, 2, report.Files[0].Entries)
+	assert.Equal(t, 1, report.Files[0].CRCFailures)
+	assert.Equal(t, 0, report.Files[0].TornRecords)
+	assert.Equal(t, int64(30), report.Files[0].DamagedBytes)
+}
+
+func TestVerify_Should_Resync_Past_Corrupted_Entry_Size(t *testing.T) {
+	dbPath, file := writeEntries(t, "foo", "bar", "baz")
+
+	defer os.RemoveAll(dbPath)
+
+	corrupt(t, file, func(b []byte) {
+		// Value size of the first entry
+		b[24+12] = 2
+	})
+
+	report, err := core.Verify(dbPath, caskfs.NewDisk(), core.Config{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.Files[0].Entries)
+	assert.Equal(t, 1, report.Files[0].CRCFailures)
+	assert.Equal(t, int64(30), report.Files[0].DamagedBytes)
+}
+
+func TestVerify_Should_Report_Torn_Record(t *testing.T) {
+	dbPath, file := writeEntries(t, "foo", "bar")
+
+	defer os.RemoveAll(dbPath)
+
+	b, err := os.ReadFile(file)
+
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(file, b[:len(b)-3], 0755))
+
+	report, err := core.Verify(dbPath, caskfs.NewDisk(), core.Config{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Files[0].Entries)
+	assert.Equal(t, 0, report.Files[0].CRCFailures)
+	assert.Equal(t, 1, report.Files[0].TornRecords)
+	assert.Equal(t, int64(27), report.Files[0].DamagedBytes)
+}
+
+func TestRepair_Should_Salvage_Valid_Entries(t *testing.T) {
+	dbPath, file := writeEntries(t, "foo", "bar", "baz")
+
+	defer os.RemoveAll(dbPath)
+
+	corrupt(t, file, func(b []byte) {
+		// Key of the second entry
+		b[24+30+24] ^= 0xff
+	})
+
+	var time testutil.Time
+
+	_, err := core.NewDB(dbPath, caskfs.NewDisk(), time, core.Config{MaxDataFileSize: 1024})
+
+	assert.ErrorIs(t, err, core.ErrCRCFailed)
+
+	report, err := core.Repair(dbPath, caskfs.NewDisk(), time, core.Config{MaxDataFileSize: 1024})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Files[0].CRCFailures)
+
+	report, err = core.Verify(dbPath, caskfs.NewDisk(), core.Config{})
+
+	assert.NoError(t, err)
+	assert.False(t, report.Damaged())
+	assert.Len(t, report.Files, 1)
+	assert.Equal(t, 2, report.Files[0].Entries)
+
+	db, err := core.NewDB(dbPath, caskfs.NewDisk(), time, core.Config{MaxDataFileSize: 1024})
+
+	assert.NoError(t, err)
+
+	defer db.Close()
+
+	assertRecovered(t, db, map[string]string{"foo": "foo", "baz": "baz"})
+}
+
+func TestRepair_Should_Rewrite_Older_Format(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_verify")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	writeFile(t, dbPath, "data_1_1.csk", legacyEntry("foo", "foo"), legacyEntry("bar", "bar"), legacyEntry("", "bar"))
+
+	var time testutil.Time
+
+	_, err = core.Repair(dbPath, caskfs.NewDisk(), time, core.Config{MaxDataFileSize: 1024})
+
+	assert.NoError(t, err)
+
+	disk := caskfs.NewDisk()
+
+	files, _ := disk.Files(dbPath)
+
+	assert.Len(t, files, 1)
+	assert.NotEqual(t, "data_1_1", files[0])
+
+	db, err := core.NewDB(dbPath, disk, time, core.Config{MaxDataFileSize: 1024})
+
+	assert.NoError(t, err)
+
+	defer db.Close()
+
+	assertRecovered(t, db, map[string]string{"foo": "foo"})
+}
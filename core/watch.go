@@ -0,0 +1,388 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// maxWatchQueue bounds the number of events queued for a watcher which does not keep up with the writes
+const maxWatchQueue = 4096
+
+// errWatchStopped signifies that the watch was canceled (or the database closed) during replay
+var errWatchStopped = errors.New("gocask: watch stopped")
+
+// EventType is the type of change reported by DB.Watch
+type EventType uint8
+
+const (
+	// EventPut is reported for every written value (including the expiring ones)
+	EventPut EventType = iota + 1
+
+	// EventDelete is reported for every deleted key. Expiry of a key is not reported
+	EventDelete
+)
+
+// Event represents a change of a single key
+type Event struct {
+	Type EventType
+	Key  []byte
+
+	// Seq is the sequence number assigned to the write
+	Seq uint64
+
+	// Timestamp is the unix time of the write
+	Timestamp uint32
+}
+
+func newEvent(key []byte, h header) Event {
+	e := Event{
+		Type:      EventPut,
+		Key:       append([]byte(nil), key...),
+		Seq:       h.Seq,
+		Timestamp: h.Timestamp,
+	}
+
+	if h.isTombstone() {
+		e.Type = EventDelete
+	}
+
+	return e
+}
+
+// watcher queues the events of the written keys with its prefix until they are sent by its goroutine,
+// so that the write path never waits for the consumer
+type watcher struct {
+	prefix []byte
+
+	m        sync.Mutex
+	queue    []Event
+	overflow bool
+
+	wake chan struct{}
+	stop chan struct{}
+}
+
+// watchers holds the registered watchers. Writes notify them under the append lock
+// so that the events are queued in order of their sequence numbers
+type watchers struct {
+	m      sync.Mutex
+	set    map[*watcher]struct{}
+	closed bool
+}
+
+// Watch reports changes of the keys with the given prefix (all keys if the prefix is empty) written
+// after the call returns. Events are sent in order of their sequence numbers.
+// The channel is closed once the context is done or the database is closed. It's closed as well if the consumer
+// falls too far behind the writes, in which case the watch can be resumed with WatchFrom from the last received Seq
+func (db *DB) Watch(ctx context.Context, prefix []byte) (<-chan Event, error) {
+	return db.watch(ctx, prefix, 0, false)
+}
+
+// WatchFrom reports changes of the keys with the given prefix written after the one with the given sequence number.
+// Writes made before the call are replayed from the data files first, so the events of the writes
+// whose entries were dropped by merge (overwritten and deleted keys) are not reported, nor are the writes
+// stored in the data file formats preceding sequence numbers. See Watch
+func (db *DB) WatchFrom(ctx context.Context, prefix []byte, seq uint64) (<-chan Event, error) {
+	return db.watch(ctx, prefix, seq, true)
+}
+
+func (db *DB) watch(ctx context.Context, prefix []byte, from uint64, replay bool) (<-chan Event, error) {
+	if db.cfg.ReadOnly {
+		return nil, ErrReadOnly
+	}
+
+	w := watcher{
+		prefix: append([]byte(nil), prefix...),
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+	}
+
+	var snap *Snapshot
+
+	if replay {
+		// Snapshot retains the data files to be replayed in case they are merged in the meantime
+		snap = db.Snapshot()
+	}
+
+	last, files, err := db.register(&w, replay)
+	if err != nil {
+		if snap != nil {
+			_ = snap.Release()
+		}
+
+		return nil, err
+	}
+
+	ch := make(chan Event)
+
+	go db.runWatcher(ctx, &w, ch, snap, files, from, last)
+
+	return ch, nil
+}
+
+// register adds the watcher so that it receives the events of all writes made after the returned sequence number.
+// Data files holding the preceding writes are listed if requested
+func (db *DB) register(w *watcher, list bool) (uint64, []string, error) {
+	db.m.RLock()
+	defer db.m.RUnlock()
+
+	db.wm.Lock()
+	defer db.wm.Unlock()
+
+	var (
+		files []string
+		err   error
+	)
+
+	if list {
+		files, err = db.liveFiles()
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+
+	ws := &db.watchers
+
+	ws.m.Lock()
+	defer ws.m.Unlock()
+
+	if ws.closed {
+		return 0, nil, ErrClosed
+	}
+
+	if ws.set == nil {
+		ws.set = map[*watcher]struct{}{}
+	}
+
+	ws.set[w] = struct{}{}
+
+	return db.seq, files, nil
+}
+
+// liveFiles lists the data files which are not retired by merge (merged data files hold their live entries).
+// Retired files are retained only until the snapshots taken before the merge are released
+func (db *DB) liveFiles() ([]string, error) {
+	files, err := db.fs.Files(db.path)
+	if err != nil {
+		return nil, err
+	}
+
+	live := files[:0]
+
+	for _, file := range files {
+		if db.retired[file] == nil {
+			live = append(live, file)
+		}
+	}
+
+	return live, nil
+}
+
+func (db *DB) runWatcher(ctx context.Context, w *watcher, ch chan<- Event, snap *Snapshot, files []string, from, last uint64) {
+	defer close(ch)
+	defer db.watchers.remove(w)
+
+	send := func(e Event) bool {
+		select {
+		case ch <- e:
+			return true
+		case <-ctx.Done():
+			return false
+		case <-w.stop:
+			return false
+		}
+	}
+
+	if snap != nil {
+		err := db.replay(files, w, from, last, send)
+
+		_ = snap.Release()
+
+		if err != nil {
+			return
+		}
+	}
+
+	for {
+		events, ok := w.take()
+
+		for _, e := range events {
+			if e.Seq <= from {
+				continue
+			}
+
+			if !send(e) {
+				return
+			}
+		}
+
+		if !ok {
+			return
+		}
+
+		select {
+		case <-w.wake:
+		case <-ctx.Done():
+			return
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// notify queues the event of the written entry for the watchers of the key
+func (ws *watchers) notify(key []byte, h header) {
+	ws.m.Lock()
+	defer ws.m.Unlock()
+
+	for w := range ws.set {
+		if bytes.HasPrefix(key, w.prefix) {
+			w.push(newEvent(key, h))
+		}
+	}
+}
+
+func (ws *watchers) remove(w *watcher) {
+	ws.m.Lock()
+	defer ws.m.Unlock()
+
+	delete(ws.set, w)
+}
+
+// close stops all watchers and prevents new ones from being registered
+func (ws *watchers) close() {
+	ws.m.Lock()
+	defer ws.m.Unlock()
+
+	ws.closed = true
+
+	for w := range ws.set {
+		close(w.stop)
+
+		delete(ws.set, w)
+	}
+}
+
+func (w *watcher) push(e Event) {
+	w.m.Lock()
+
+	if len(w.queue) < maxWatchQueue {
+		w.queue = append(w.queue, e)
+	} else {
+		w.overflow = true
+	}
+
+	w.m.Unlock()
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// take dequeues the queued events and reports false if some events were dropped after them
+func (w *watcher) take() ([]Event, bool) {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	events := w.queue
+
+	w.queue = nil
+
+	return events, !w.overflow
+}
+
+// replay sends the events of the entries of the data files whose sequence numbers are in (from, last]
+func (db *DB) replay(files []string, w *watcher, from, last uint64, send func(Event) bool) error {
+	rp := replayer{
+		prefix: w.prefix,
+		from:   from,
+		last:   last,
+		send:   send,
+	}
+
+	for i, file := range files {
+		select {
+		case <-w.stop:
+			return errWatchStopped
+		default:
+		}
+
+		// Entries of a data file precede the sequence number stored in the header of the next one
+		if i+1 < len(files) {
+			fh, err := db.readFileHeader(files[i+1])
+			if err == nil && fh.Seq <= from {
+				continue
+			}
+		}
+
+		r := bufio.NewReader(&fileReader{
+			fs:   db.fs,
+			path: db.path,
+			file: file,
+		})
+
+		fh, err := readFileHeader(r)
+		if err != nil {
+			if errors.Is(err, errEmptyFile) || errors.Is(err, errTornFileHeader) {
+				continue
+			}
+
+			return err
+		}
+
+		done, err := rp.replayEntries(newEntryReader(r, uint64(fh.size()), fh.valueCRC(), false))
+		if done || err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// replayer sends the events of the replayed entries in order
+type replayer struct {
+	prefix     []byte
+	from, last uint64
+	send       func(Event) bool
+}
+
+// replayEntries reports true once it reaches an entry written after the last replayed sequence number
+func (rp *replayer) replayEntries(er *entryReader) (bool, error) {
+	for {
+		e, err := er.next()
+
+		// Entries are replayed the way they were read upon startup, see readEntries
+		if err != nil && !errors.Is(err, errCorruptedValue) {
+			if isCorrupted(err) {
+				continue
+			}
+
+			if errors.Is(err, io.EOF) || isTorn(err) {
+				return false, nil
+			}
+
+			return false, err
+		}
+
+		if e.h.Seq > rp.last {
+			return true, nil
+		}
+
+		key := e.target()
+
+		if e.h.Seq <= rp.from || !bytes.HasPrefix(key, rp.prefix) {
+			continue
+		}
+
+		rp.from = e.h.Seq
+
+		if !rp.send(newEvent(key, e.h)) {
+			return false, errWatchStopped
+		}
+	}
+}
@@ -0,0 +1,424 @@
+package core_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/aneshas/gocask/core"
+	"github.com/aneshas/gocask/core/testutil"
+	caskfs "github.com/aneshas/gocask/internal/fs"
+	"github.com/stretchr/testify/assert"
+	"os"
+	gopath "path"
+	"testing"
+	gotime "time"
+)
+
+func receive(t *testing.T, ch <-chan core.Event, n int) []core.Event {
+	var events []core.Event
+
+	for len(events) < n {
+		select {
+		case e, ok := <-ch:
+			if !assert.True(t, ok, "watch closed") {
+				return events
+			}
+
+			events = append(events, e)
+		case <-gotime.After(5 * gotime.Second):
+			assert.Fail(t, "timed out waiting for events")
+
+			return events
+		}
+	}
+
+	return events
+}
+
+func assertClosed(t *testing.T, ch <-chan core.Event) {
+	select {
+	case _, ok := <-ch:
+		assert.False(t, ok)
+	case <-gotime.After(5 * gotime.Second):
+		assert.Fail(t, "watch not closed")
+	}
+}
+
+func TestWatch_Should_Report_Changes_Of_Keys_With_Prefix(t *testing.T) {
+	var time testutil.Time
+
+	db := openDB(t, "", caskfs.NewInMemory(), time, core.Config{MaxDataFileSize: 1024})
+
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	ch, err := db.Watch(ctx, []byte("user/"))
+
+	assert.NoError(t, err)
+
+	seq, err := db.Put([]byte("user/foo"), []byte("foo"))
+
+	assert.NoError(t, err)
+
+	_, err = db.Put([]byte("order/foo"), []byte("foo"))
+
+	assert.NoError(t, err)
+
+	assert.NoError(t, db.Delete([]byte("user/foo")))
+
+	var b core.Batch
+
+	b.Put([]byte("user/bar"), []byte("bar"))
+	b.Delete([]byte("order/foo"))
+	b.Put([]byte("user/baz"), []byte("baz"))
+
+	assert.NoError(t, db.Write(&b))
+
+	events := receive(t, ch, 4)
+
+	assert.Equal(t, []core.Event{
+		{Type: core.EventPut, Key: []byte("user/foo"), Seq: seq, Timestamp: time.NowUnix()},
+		{Type: core.EventDelete, Key: []byte("user/foo"), Seq: seq + 2, Timestamp: time.NowUnix()},
+		{Type: core.EventPut, Key: []byte("user/bar"), Seq: seq + 3, Timestamp: time.NowUnix()},
+		{Type: core.EventPut, Key: []byte("user/baz"), Seq: seq + 5, Timestamp: time.NowUnix()},
+	}, events)
+}
+
+func TestWatch_Should_Report_Changes_Which_Can_Be_Read(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_watch")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	var time testutil.Time
+
+	// Values are read while being written, which in-memory FS does not support
+	db := openDB(t, dbPath, caskfs.NewDisk(), time, core.Config{MaxDataFileSize: 1024 * 1024})
+
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	ch, err := db.Watch(ctx, nil)
+
+	assert.NoError(t, err)
+
+	const n = 10000
+
+	// Writer is kept ahead of the consumer only so far, so that the watch does not fall too far behind the writes
+	window := make(chan struct{}, 64)
+
+	go func() {
+		for i := 0; i < n; i++ {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			key, val := []byte(fmt.Sprintf("key_%d", i)), []byte(fmt.Sprintf("val_%d", i))
+
+			if i%2 == 0 {
+				_, _ = db.Put(key, val)
+
+				continue
+			}
+
+			var b core.Batch
+
+			b.Put(key, val)
+
+			_ = db.Write(&b)
+		}
+	}()
+
+	// Keys are read back as soon as their events are received, while the writes are still going on
+	for i := 0; i < n; i++ {
+		events := receive(t, ch, 1)
+		if len(events) == 0 {
+			return
+		}
+
+		e := events[0]
+
+		val, version, err := db.GetWithVersion(e.Key)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, e.Seq, version)
+		assert.Equal(t, "val_"+string(e.Key[len("key_"):]), string(val))
+
+		<-window
+	}
+}
+
+func TestWatch_Should_Be_Closed_When_Context_Is_Done(t *testing.T) {
+	var time testutil.Time
+
+	db := openDB(t, "", caskfs.NewInMemory(), time, core.Config{MaxDataFileSize: 1024})
+
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ch, err := db.Watch(ctx, nil)
+
+	assert.NoError(t, err)
+
+	cancel()
+
+	assertClosed(t, ch)
+
+	// Writes do not wait for the canceled watch
+	_, err = db.Put([]byte("foo"), []byte("foo"))
+
+	assert.NoError(t, err)
+}
+
+func TestWatch_Should_Be_Closed_When_Database_Is_Closed(t *testing.T) {
+	var time testutil.Time
+
+	db := openDB(t, "", caskfs.NewInMemory(), time, core.Config{MaxDataFileSize: 1024})
+
+	ch, err := db.Watch(context.Background(), nil)
+
+	assert.NoError(t, err)
+
+	assert.NoError(t, db.Close())
+
+	assertClosed(t, ch)
+
+	_, err = db.Watch(context.Background(), nil)
+
+	assert.ErrorIs(t, err, core.ErrClosed)
+}
+
+func TestWatch_Should_Be_Closed_When_Consumer_Falls_Behind(t *testing.T) {
+	var time testutil.Time
+
+	db := openDB(t, "", caskfs.NewInMemory(), time, core.Config{MaxDataFileSize: 1024 * 1024})
+
+	defer db.Close()
+
+	ch, err := db.Watch(context.Background(), nil)
+
+	assert.NoError(t, err)
+
+	// Watcher may take the queued events out while the batch is being applied, which doubles its capacity
+	const n = 10000
+
+	var b core.Batch
+
+	for i := 0; i < n; i++ {
+		b.Put([]byte(fmt.Sprintf("key_%d", i)), []byte("val"))
+	}
+
+	assert.NoError(t, db.Write(&b))
+
+	var last uint64
+
+	for e := range ch {
+		assert.Equal(t, last+1, e.Seq)
+
+		last = e.Seq
+	}
+
+	// Events queued before the overflow are delivered
+	assert.Less(t, last, uint64(n))
+	assert.GreaterOrEqual(t, last, uint64(4096))
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	ch, err = db.WatchFrom(ctx, nil, last)
+
+	assert.NoError(t, err)
+
+	events := receive(t, ch, int(n-last))
+
+	assert.Equal(t, last+1, events[0].Seq)
+	assert.Equal(t, uint64(n), events[len(events)-1].Seq)
+}
+
+func TestWatchFrom_Should_Replay_Writes_From_Data_Files(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_watch")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	db := openDB(t, dbPath, caskfs.NewDisk(), &testutil.Clock{Now: 100}, core.Config{MaxDataFileSize: 128})
+
+	for i := 0; i < 10; i++ {
+		key := []byte(fmt.Sprintf("key_%d", i%3))
+
+		_, err = db.Put(key, key)
+
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, db.Delete([]byte("key_0")))
+	assert.NoError(t, db.Close())
+
+	db = openDB(t, dbPath, caskfs.NewDisk(), &testutil.Clock{Now: 100}, core.Config{MaxDataFileSize: 128})
+
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	ch, err := db.WatchFrom(ctx, []byte("key_1"), 2)
+
+	assert.NoError(t, err)
+
+	seq, err := db.Put([]byte("key_1"), []byte("key_1"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(12), seq)
+
+	events := receive(t, ch, 3)
+
+	var seqs []uint64
+
+	for _, e := range events {
+		assert.Equal(t, core.EventPut, e.Type)
+		assert.Equal(t, []byte("key_1"), e.Key)
+		assert.Equal(t, uint32(100), e.Timestamp)
+
+		seqs = append(seqs, e.Seq)
+	}
+
+	assert.Equal(t, []uint64{5, 8, 12}, seqs)
+
+	ch, err = db.WatchFrom(ctx, []byte("key_0"), 9)
+
+	assert.NoError(t, err)
+
+	events = receive(t, ch, 2)
+
+	assert.Equal(t, core.EventPut, events[0].Type)
+	assert.Equal(t, uint64(10), events[0].Seq)
+	assert.Equal(t, core.EventDelete, events[1].Type)
+	assert.Equal(t, uint64(11), events[1].Seq)
+}
+
+func TestWatchFrom_Should_Replay_Live_Entries_After_Merge(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_watch")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	db := openDB(t, dbPath, caskfs.NewDisk(), &testutil.Clock{Now: 100}, core.Config{MaxDataFileSize: 128})
+
+	defer db.Close()
+
+	for i := 0; i < 12; i++ {
+		key := []byte(fmt.Sprintf("key_%d", i%4))
+
+		_, err = db.Put(key, key)
+
+		assert.NoError(t, err)
+	}
+
+	// Merged data files retained for the snapshot are not replayed
+	s := db.Snapshot()
+
+	assert.NoError(t, db.Merge())
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	ch, err := db.WatchFrom(ctx, nil, 0)
+
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.Release())
+
+	// Only the last writes of the keys are left after the merge
+	events := receive(t, ch, 4)
+
+	for i, e := range events {
+		assert.Equal(t, uint64(i+9), e.Seq)
+	}
+
+	_, err = db.Put([]byte("foo"), []byte("foo"))
+
+	assert.NoError(t, err)
+
+	events = receive(t, ch, 1)
+
+	assert.Equal(t, uint64(13), events[0].Seq)
+}
+
+func TestWatchFrom_Should_Not_Replay_Corrupted_Entries(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_watch")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	disk := caskfs.NewDisk()
+
+	db := openDB(t, dbPath, disk, &testutil.Clock{Now: 100}, core.Config{MaxDataFileSize: 96})
+
+	defer db.Close()
+
+	for _, key := range []string{"foo", "bar", "baz"} {
+		_, err := db.Put([]byte(key), []byte(key))
+
+		assert.NoError(t, err)
+	}
+
+	files, _ := disk.Files(dbPath)
+
+	corrupt(t, gopath.Join(dbPath, files[0]+".csk"), func(b []byte) {
+		// Value of the first entry
+		b[24+24+3] ^= 0xff
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	ch, err := db.WatchFrom(ctx, nil, 0)
+
+	assert.NoError(t, err)
+
+	events := receive(t, ch, 2)
+
+	assert.Equal(t, []byte("bar"), events[0].Key)
+	assert.Equal(t, []byte("baz"), events[1].Key)
+}
+
+func TestWatch_Should_Not_Be_Supported_In_Read_Only_Mode(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "gocask_watch")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	db := openDB(t, dbPath, caskfs.NewDisk(), &testutil.Clock{Now: 100}, core.Config{MaxDataFileSize: 128})
+
+	defer db.Close()
+
+	var time testutil.Time
+
+	ro := openDB(t, dbPath, caskfs.NewDisk(), time, core.Config{MaxDataFileSize: 128, ReadOnly: true})
+
+	defer ro.Close()
+
+	_, err = ro.Watch(context.Background(), nil)
+
+	assert.ErrorIs(t, err, core.ErrReadOnly)
+}
@@ -3,6 +3,7 @@ package gocask
 import (
 	"github.com/aneshas/gocask/core"
 	"github.com/aneshas/gocask/internal/fs"
+	"log"
 	"os"
 	"path"
 	"time"
@@ -27,36 +28,74 @@ const (
 // Magic in:mem:db value for dbPath can be used in order to instantiate an in memory file system
 // which can be used for testing purposes.
 func Open(dbPath string, opts ...Option) (*core.DB, error) {
-	var caskFS core.FS
+	var t goTime
 
-	caskFS = fs.NewDisk()
+	cfg, err := config(opts)
+	if err != nil {
+		return nil, err
+	}
 
-	if dbPath == core.InMemoryDB {
-		caskFS = fs.NewInMemory()
+	db, err := core.NewDB(dbPath, fileSystem(dbPath, cfg), t, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Verify scans all data files of the database at dbPath and reports their integrity.
+// It accepts the same options as Open
+func Verify(dbPath string, opts ...Option) (*core.Report, error) {
+	cfg, err := config(opts)
+	if err != nil {
+		return nil, err
 	}
 
+	return core.Verify(dbPath, fileSystem(dbPath, cfg), cfg)
+}
+
+// Repair salvages all valid entries of the database at dbPath into a fresh set of data files,
+// skipping damaged regions. It accepts the same options as Open
+func Repair(dbPath string, opts ...Option) (*core.Report, error) {
 	var t goTime
 
-	home, err := os.UserHomeDir()
+	cfg, err := config(opts)
 	if err != nil {
 		return nil, err
 	}
 
+	return core.Repair(dbPath, fileSystem(dbPath, cfg), t, cfg)
+}
+
+func fileSystem(dbPath string, cfg core.Config) core.FS {
+	if dbPath == core.InMemoryDB {
+		return fs.NewInMemory()
+	}
+
+	if cfg.MMap {
+		return fs.NewMMap()
+	}
+
+	return fs.NewDisk()
+}
+
+func config(opts []Option) (core.Config, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return core.Config{}, err
+	}
+
 	cfg := core.Config{
 		MaxDataFileSize: 10 * GB,
 		DataDir:         path.Join(home, "gcdata"),
+		Logger:          log.Default(),
 	}
 
 	for _, opt := range opts {
 		cfg = opt(cfg)
 	}
 
-	db, err := core.NewDB(dbPath, caskFS, t, cfg)
-	if err != nil {
-		return nil, err
-	}
-
-	return db, nil
+	return cfg, nil
 }
 
 // Option represents gocask configuration option
@@ -72,6 +111,77 @@ func WithMaxDataFileSize(bytes int64) Option {
 	}
 }
 
+// WithSyncPolicy configures when written data is flushed to durable storage
+// eg. core.SyncAlways, core.SyncEvery(100 * time.Millisecond) or core.SyncNever (default)
+func WithSyncPolicy(policy core.SyncPolicy) Option {
+	return func(config core.Config) core.Config {
+		config.SyncPolicy = policy
+
+		return config
+	}
+}
+
+// WithScrubPolicy enables background scrubbing of immutable data files
+// eg. core.ScrubPolicy{Interval: 24 * time.Hour, BytesPerSecond: 10 * gocask.MB, OnCorruption: alert}
+func WithScrubPolicy(policy core.ScrubPolicy) Option {
+	return func(config core.Config) core.Config {
+		config.Scrub = policy
+
+		return config
+	}
+}
+
+// WithReadOnly opens the database in read-only mode, which does not lock the database,
+// so it can be opened by other processes (including the one writing to it).
+// Use DB.Refresh to read the entries written after the database was opened
+func WithReadOnly() Option {
+	return func(config core.Config) core.Config {
+		config.ReadOnly = true
+
+		return config
+	}
+}
+
+// WithStrictMode refuses to open the database with core.ErrTornTail if a data file ends with
+// an incomplete or corrupted entry, instead of truncating the entry (which is the default)
+func WithStrictMode() Option {
+	return func(config core.Config) core.Config {
+		config.Strict = true
+
+		return config
+	}
+}
+
+// WithLogger configures the logger used to log recovery actions taken upon opening the database
+// (log.Default() by default). Pass nil to disable logging
+func WithLogger(logger core.Logger) Option {
+	return func(config core.Config) core.Config {
+		config.Logger = logger
+
+		return config
+	}
+}
+
+// WithMMap reads immutable (rotated) data files through read-only memory mappings,
+// which avoids a syscall per read. The active data file is still read with ReadAt
+func WithMMap() Option {
+	return func(config core.Config) core.Config {
+		config.MMap = true
+
+		return config
+	}
+}
+
+// WithValueCache enables an in-memory cache of values read by Get and written by Put,
+// bounded by the total size of the values (see DB.CacheStats for its hit ratio)
+func WithValueCache(bytes int64) Option {
+	return func(config core.Config) core.Config {
+		config.ValueCacheSize = bytes
+
+		return config
+	}
+}
+
 // WithDataDir configures the location of the data dir where your databases will reside
 func WithDataDir(path string) Option {
 	return func(config core.Config) core.Config {
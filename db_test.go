@@ -8,6 +8,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"os"
 	"path"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
@@ -36,6 +37,27 @@ func TestDisk_DB_Should_Store_And_Retrieve_A_Set_Of_Key_Val_Pairs(t *testing.T)
 	writeReadAndAssert(t, db)
 }
 
+func TestMMap_DB_Should_Store_And_Retrieve_A_Set_Of_Key_Val_Pairs(t *testing.T) {
+	dbPath, err := os.MkdirTemp("", "cask_db_mmap")
+
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dbPath)
+
+	db, err := gocask.Open(
+		path.Base(dbPath),
+		gocask.WithDataDir(path.Dir(dbPath)),
+		gocask.WithMaxDataFileSize(4*gocask.KB),
+		gocask.WithMMap(),
+	)
+
+	assert.NoError(t, err)
+
+	defer db.Close()
+
+	writeReadAndAssert(t, db)
+}
+
 func writeReadAndAssert(t *testing.T, db *core.DB) {
 	file, err := os.Open("testdata/data.txt")
 
@@ -56,7 +78,7 @@ func writeReadAndAssert(t *testing.T, db *core.DB) {
 
 		entries[parts[0]] = val
 
-		err := db.Put([]byte(parts[0]), val)
+		_, err := db.Put([]byte(parts[0]), val)
 
 		assert.NoError(t, err)
 	}
@@ -111,10 +133,176 @@ func benchDiskPut(b *testing.B, n int) {
 	for i := 0; i < b.N; i++ {
 		for j := 0; j < n; j++ {
 			// TODO Larger value
-			err := db.Put([]byte("user:123456"), []byte("lorem ipsum sit dolor amet - lorem ipsum sit dolor amet"))
+			_, err := db.Put([]byte("user:123456"), []byte("lorem ipsum sit dolor amet - lorem ipsum sit dolor amet"))
 			if err != nil {
 				b.Fatal(err)
 			}
 		}
 	}
 }
+
+// BenchmarkDisk_Get_Parallel_With_Writes measures parallel reads while the keys are being overwritten
+func BenchmarkDisk_Get_Parallel_With_Writes(b *testing.B) {
+	dataDir, err := os.MkdirTemp("", "gocask_get")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	defer os.RemoveAll(dataDir)
+
+	db, err := gocask.Open("db", gocask.WithDataDir(dataDir))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	defer db.Close()
+
+	const keys = 10000
+
+	for i := 0; i < keys; i++ {
+		_, err := db.Put([]byte(fmt.Sprintf("user:%08d", i)), []byte("lorem ipsum"))
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			_, _ = db.Put([]byte(fmt.Sprintf("user:%08d", i%keys)), []byte("lorem ipsum"))
+		}
+	}()
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+
+		for pb.Next() {
+			_, err := db.Get([]byte(fmt.Sprintf("user:%08d", i%keys)))
+			if err != nil {
+				b.Error(err)
+
+				return
+			}
+
+			i++
+		}
+	})
+
+	b.StopTimer()
+
+	close(done)
+	<-stopped
+}
+
+// BenchmarkKeyDir_Bytes_Per_Key reports the heap taken by the keydir loaded upon opening a database
+func BenchmarkKeyDir_Bytes_Per_Key(b *testing.B) {
+	const keys = 200000
+
+	dataDir, err := os.MkdirTemp("", "gocask_keydir")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	defer os.RemoveAll(dataDir)
+
+	db, err := gocask.Open("db", gocask.WithDataDir(dataDir), gocask.WithMaxDataFileSize(64*gocask.MB))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for i := 0; i < keys; i++ {
+		_, err := db.Put([]byte(fmt.Sprintf("user:%08d", i)), []byte("lorem ipsum"))
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	err = db.Close()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+
+	var bytes uint64
+
+	for i := 0; i < b.N; i++ {
+		var before, after runtime.MemStats
+
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+
+		db, err := gocask.Open("db", gocask.WithDataDir(dataDir), gocask.WithMaxDataFileSize(64*gocask.MB))
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+
+		bytes += after.HeapAlloc - before.HeapAlloc
+
+		err = db.Close()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ReportMetric(float64(bytes)/float64(b.N)/keys, "bytes/key")
+}
+
+// BenchmarkKeyDir_Sorted_Iteration measures visiting all keys of the database in order
+func BenchmarkKeyDir_Sorted_Iteration(b *testing.B) {
+	const keys = 200000
+
+	dataDir, err := os.MkdirTemp("", "gocask_sorted")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	defer os.RemoveAll(dataDir)
+
+	db, err := gocask.Open("db", gocask.WithDataDir(dataDir), gocask.WithMaxDataFileSize(64*gocask.MB))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	defer db.Close()
+
+	for i := 0; i < keys; i++ {
+		_, err := db.Put([]byte(fmt.Sprintf("user:%08d", i)), []byte("lorem ipsum"))
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		it := db.Iterator(core.Sorted())
+
+		n := 0
+
+		for it.Next() {
+			n++
+		}
+
+		_ = it.Close()
+
+		if n != keys {
+			b.Fatalf("visited %d keys, want %d", n, keys)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+// Package btree implements an in-memory B-tree of item ids which is used
+// as an ordered index of keys. Keys themselves are kept by the user of the tree
+// which orders the items by their keys, so that the tree takes just a few bytes per key
+package btree
+
+import "sort"
+
+const (
+	// degree is the minimum degree of the tree - every node except the root
+	// holds at least degree-1 and at most 2*degree-1 items
+	degree   = 32
+	maxItems = 2*degree - 1
+)
+
+// Less reports whether item a is ordered before item b. Items which are ordered
+// before neither of each other are considered equal
+type Less func(a, b uint32) bool
+
+// BTree represents an ordered set of items
+type BTree struct {
+	root *node
+	len  int
+	less Less
+}
+
+// New instantiates new empty B-tree whose items are ordered by less
+func New(less Less) *BTree {
+	return &BTree{
+		less: less,
+	}
+}
+
+// Len returns the number of items in the tree
+func (t *BTree) Len() int {
+	return t.len
+}
+
+// Insert adds item to the tree and reports whether it was not already present
+func (t *BTree) Insert(item uint32) bool {
+	if t.root == nil {
+		t.root = &node{items: []uint32{item}}
+		t.len++
+
+		return true
+	}
+
+	if len(t.root.items) == maxItems {
+		t.root = &node{children: []*node{t.root}}
+		t.root.split(0)
+	}
+
+	if !t.root.insert(item, t.less) {
+		return false
+	}
+
+	t.len++
+
+	return true
+}
+
+// Delete removes item from the tree and reports whether it was present
+func (t *BTree) Delete(item uint32) bool {
+	if t.root == nil {
+		return false
+	}
+
+	removed := t.root.remove(item, t.less)
+
+	if len(t.root.items) == 0 {
+		if t.root.leaf() {
+			t.root = nil
+		} else {
+			t.root = t.root.children[0]
+		}
+	}
+
+	if removed {
+		t.len--
+	}
+
+	return removed
+}
+
+// Has reports whether item is present in the tree
+func (t *BTree) Has(item uint32) bool {
+	n := t.root
+
+	for n != nil {
+		i, found := n.find(item, t.less)
+		if found {
+			return true
+		}
+
+		if n.leaf() {
+			return false
+		}
+
+		n = n.children[i]
+	}
+
+	return false
+}
+
+// Ascend calls fn for every item which does not precede the pivot in ascending order until fn returns false.
+// Pivot need not be an item of the tree, so it's given as a function reporting whether the item precedes it
+func (t *BTree) Ascend(precedes func(item uint32) bool, fn func(item uint32) bool) {
+	if t.root == nil {
+		return
+	}
+
+	t.root.ascend(precedes, fn)
+}
+
+type node struct {
+	items    []uint32
+	children []*node
+}
+
+func (n *node) leaf() bool {
+	return len(n.children) == 0
+}
+
+func (n *node) find(item uint32, less Less) (int, bool) {
+	i := n.search(func(it uint32) bool {
+		return less(it, item)
+	})
+
+	return i, i < len(n.items) && !less(item, n.items[i])
+}
+
+// search returns the position of the first item which does not precede the pivot
+func (n *node) search(precedes func(uint32) bool) int {
+	return sort.Search(len(n.items), func(i int) bool {
+		return !precedes(n.items[i])
+	})
+}
+
+// split splits the full child at position i in two moving its middle item up to n
+func (n *node) split(i int) {
+	child := n.children[i]
+	mid := child.items[degree-1]
+
+	right := &node{
+		items: append([]uint32(nil), child.items[degree:]...),
+	}
+
+	child.items = child.items[:degree-1]
+
+	if !child.leaf() {
+		right.children = append([]*node(nil), child.children[degree:]...)
+
+		truncateChildren(&child.children, degree)
+	}
+
+	insertItem(&n.items, i, mid)
+	insertChild(&n.children, i+1, right)
+}
+
+func (n *node) insert(item uint32, less Less) bool {
+	i, found := n.find(item, less)
+	if found {
+		return false
+	}
+
+	if n.leaf() {
+		insertItem(&n.items, i, item)
+
+		return true
+	}
+
+	if len(n.children[i].items) == maxItems {
+		n.split(i)
+
+		switch {
+		case less(n.items[i], item):
+			i++
+		case !less(item, n.items[i]):
+			return false
+		}
+	}
+
+	return n.children[i].insert(item, less)
+}
+
+// remove removes item from the subtree rooted at n.
+// Unless n is the root, it's guaranteed to hold at least degree items
+func (n *node) remove(item uint32, less Less) bool {
+	i, found := n.find(item, less)
+
+	if n.leaf() {
+		if !found {
+			return false
+		}
+
+		removeItem(&n.items, i)
+
+		return true
+	}
+
+	if found {
+		switch {
+		case len(n.children[i].items) >= degree:
+			pred := n.children[i].max()
+			n.items[i] = pred
+
+			return n.children[i].remove(pred, less)
+		case len(n.children[i+1].items) >= degree:
+			succ := n.children[i+1].min()
+			n.items[i] = succ
+
+			return n.children[i+1].remove(succ, less)
+		}
+
+		n.merge(i)
+
+		return n.children[i].remove(item, less)
+	}
+
+	if len(n.children[i].items) < degree {
+		i = n.grow(i)
+	}
+
+	return n.children[i].remove(item, less)
+}
+
+// grow makes sure that the child at position i holds at least degree items either by
+// borrowing an item from one of its siblings or by merging it with one of them.
+// It returns the new position of the child
+func (n *node) grow(i int) int {
+	child := n.children[i]
+
+	if i > 0 && len(n.children[i-1].items) >= degree {
+		left := n.children[i-1]
+
+		insertItem(&child.items, 0, n.items[i-1])
+		n.items[i-1] = left.items[len(left.items)-1]
+		removeItem(&left.items, len(left.items)-1)
+
+		if !left.leaf() {
+			insertChild(&child.children, 0, left.children[len(left.children)-1])
+			removeChild(&left.children, len(left.children)-1)
+		}
+
+		return i
+	}
+
+	if i < len(n.items) && len(n.children[i+1].items) >= degree {
+		right := n.children[i+1]
+
+		child.items = append(child.items, n.items[i])
+		n.items[i] = right.items[0]
+		removeItem(&right.items, 0)
+
+		if !right.leaf() {
+			child.children = append(child.children, right.children[0])
+			removeChild(&right.children, 0)
+		}
+
+		return i
+	}
+
+	if i == len(n.items) {
+		i--
+	}
+
+	n.merge(i)
+
+	return i
+}
+
+// merge merges the child at position i+1 and the item at position i into the child at position i
+func (n *node) merge(i int) {
+	left, right := n.children[i], n.children[i+1]
+
+	left.items = append(left.items, n.items[i])
+	left.items = append(left.items, right.items...)
+	left.children = append(left.children, right.children...)
+
+	removeItem(&n.items, i)
+	removeChild(&n.children, i+1)
+}
+
+func (n *node) min() uint32 {
+	for !n.leaf() {
+		n = n.children[0]
+	}
+
+	return n.items[0]
+}
+
+func (n *node) max() uint32 {
+	for !n.leaf() {
+		n = n.children[len(n.children)-1]
+	}
+
+	return n.items[len(n.items)-1]
+}
+
+func (n *node) ascend(precedes func(uint32) bool, fn func(uint32) bool) bool {
+	i := n.search(precedes)
+
+	for ; i < len(n.items); i++ {
+		if !n.leaf() && !n.children[i].ascend(precedes, fn) {
+			return false
+		}
+
+		if !fn(n.items[i]) {
+			return false
+		}
+	}
+
+	if n.leaf() {
+		return true
+	}
+
+	return n.children[i].ascend(precedes, fn)
+}
+
+func insertItem(items *[]uint32, i int, item uint32) {
+	*items = append(*items, 0)
+
+	copy((*items)[i+1:], (*items)[i:])
+
+	(*items)[i] = item
+}
+
+func removeItem(items *[]uint32, i int) {
+	copy((*items)[i:], (*items)[i+1:])
+
+	*items = (*items)[:len(*items)-1]
+}
+
+func insertChild(children *[]*node, i int, child *node) {
+	*children = append(*children, nil)
+
+	copy((*children)[i+1:], (*children)[i:])
+
+	(*children)[i] = child
+}
+
+func removeChild(children *[]*node, i int) {
+	copy((*children)[i:], (*children)[i+1:])
+
+	truncateChildren(children, len(*children)-1)
+}
+
+func truncateChildren(children *[]*node, n int) {
+	for i := n; i < len(*children); i++ {
+		(*children)[i] = nil
+	}
+
+	*children = (*children)[:n]
+}
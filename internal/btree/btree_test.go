@@ -0,0 +1,123 @@
+package btree_test
+
+import (
+	"fmt"
+	"github.com/aneshas/gocask/internal/btree"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestBTree_Should_Keep_Items_In_Order(t *testing.T) {
+	keys := numbered("key%d", 5000)
+	tree := btree.New(byKey(keys))
+	present := make(map[uint32]bool)
+	rnd := rand.New(rand.NewSource(42))
+
+	for i := 0; i < 20000; i++ {
+		item := uint32(rnd.Intn(len(keys)))
+
+		if rnd.Intn(3) == 0 {
+			assert.Equal(t, present[item], tree.Delete(item))
+
+			delete(present, item)
+
+			continue
+		}
+
+		assert.Equal(t, !present[item], tree.Insert(item))
+
+		present[item] = true
+	}
+
+	var want []string
+
+	for item := range present {
+		want = append(want, keys[item])
+	}
+
+	sort.Strings(want)
+
+	assert.Equal(t, len(want), tree.Len())
+	assert.Equal(t, want, ascend(tree, keys, ""))
+
+	for item := range present {
+		assert.True(t, tree.Has(item))
+		assert.True(t, tree.Delete(item))
+	}
+
+	assert.Equal(t, 0, tree.Len())
+	assert.Nil(t, ascend(tree, keys, ""))
+}
+
+func TestBTree_Should_Ascend_From_Given_Key(t *testing.T) {
+	keys := numbered("%04d", 1000)
+	tree := btree.New(byKey(keys))
+
+	for i := range keys {
+		tree.Insert(uint32(i))
+	}
+
+	got := ascend(tree, keys, "0995")
+
+	assert.Equal(t, []string{"0995", "0996", "0997", "0998", "0999"}, got)
+
+	got = ascend(tree, keys, "0500a")
+
+	assert.Equal(t, "0501", got[0])
+	assert.Len(t, got, 499)
+}
+
+func TestBTree_Should_Stop_Ascending(t *testing.T) {
+	keys := numbered("%04d", 1000)
+	tree := btree.New(byKey(keys))
+
+	for i := len(keys) - 1; i >= 0; i-- {
+		tree.Insert(uint32(i))
+	}
+
+	var got []string
+
+	tree.Ascend(before(keys, "0100"), func(item uint32) bool {
+		got = append(got, keys[item])
+
+		return len(got) < 3
+	})
+
+	assert.Equal(t, []string{"0100", "0101", "0102"}, got)
+}
+
+func numbered(format string, n int) []string {
+	keys := make([]string, n)
+
+	for i := range keys {
+		keys[i] = fmt.Sprintf(format, i)
+	}
+
+	return keys
+}
+
+func byKey(keys []string) btree.Less {
+	return func(a, b uint32) bool {
+		return keys[a] < keys[b]
+	}
+}
+
+func before(keys []string, from string) func(uint32) bool {
+	return func(item uint32) bool {
+		return keys[item] < from
+	}
+}
+
+func ascend(tree *btree.BTree, keys []string, from string) []string {
+	var got []string
+
+	tree.Ascend(before(keys, from), func(item uint32) bool {
+		got = append(got, keys[item])
+
+		return true
+	})
+
+	return got
+}
@@ -8,3 +8,8 @@ const ieee = 0xedb88320
 func CalcCRC32(val []byte) uint32 {
 	return crc32.Checksum(val, crc32.MakeTable(ieee))
 }
+
+// UpdateCRC32 returns the result of adding the bytes in val to the crc
+func UpdateCRC32(crc uint32, val []byte) uint32 {
+	return crc32.Update(crc, crc32.MakeTable(ieee), val)
+}
@@ -4,12 +4,23 @@ import (
 	"errors"
 	"fmt"
 	"github.com/aneshas/gocask/core"
-	gofs "io/fs"
 	"os"
 	gopath "path"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
+)
+
+const (
+	dataFileExt  = ".csk"
+	hintFileExt  = ".hint"
+	mergeFileExt = ".merge"
+	tmpFileExt   = ".tmp"
+	lockFileName = "gocask.lock"
 )
 
 // DiskFile represents file on disk
@@ -21,9 +32,7 @@ type DiskFile struct {
 
 // Name returns the base name of the file (without path and/or extension)
 func (f *DiskFile) Name() string {
-	baseName := filepath.Base(f.File.Name())
-
-	return strings.TrimSuffix(baseName, filepath.Ext(baseName))
+	return dataFileName(f.File.Name())
 }
 
 // Size returns current data file size in kb
@@ -38,48 +47,160 @@ func (f *DiskFile) Write(p []byte) (int, error) {
 	return f.File.Write(p)
 }
 
+// Truncate delegates truncation to the underlying file and updates file size
+func (f *DiskFile) Truncate(size int64) error {
+	err := f.File.Truncate(size)
+	if err != nil {
+		return err
+	}
+
+	f.size = size
+
+	return nil
+}
+
 // NewDisk instantiates new disk based file system
 func NewDisk() *Disk {
-	return &Disk{}
+	return &Disk{
+		locks: make(map[string]*os.File),
+		handles: handleCache{
+			max: defaultReadHandles,
+		},
+	}
 }
 
 // Disk represents disk based file system
-type Disk struct{}
+type Disk struct {
+	m     sync.Mutex
+	locks map[string]*os.File
+
+	// handles keeps data files open for reading by ReadFileAt
+	handles handleCache
+}
 
-// Open opens a default data file for reading and creates it if it does not exist
+// WithReadHandles configures the max number of data files kept open for reading (64 by default).
+// Data files are opened upon every read if n is not positive
+func (fs *Disk) WithReadHandles(n int) *Disk {
+	fs.handles.max = n
+
+	return fs
+}
+
+// Open opens a default data file for reading and creates it if it does not exist.
+// Database directory is locked for exclusive use until Close is called
+// and core.ErrDatabaseLocked is returned if it's already in use by another process
 func (fs *Disk) Open(path string) (core.File, error) {
 	err := fs.createDir(path)
 	if err != nil {
 		return nil, err
 	}
 
-	entries, err := os.ReadDir(path)
+	err = fs.lock(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := fs.openActive(path)
+	if err != nil {
+		_ = fs.Close(path)
+
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func (fs *Disk) openActive(path string) (core.File, error) {
+	err := fs.removePending(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := fs.Files(path)
 	if err != nil {
 		return nil, err
 	}
 
 	var dataFile string
 
-	if len(entries) > 0 {
-		dataFile = entries[len(entries)-1].Name()
+	if len(files) > 0 {
+		dataFile = files[len(files)-1] + dataFileExt
+	}
+
+	return fs.openFile(path, dataFile, nextFileID(files))
+}
+
+// Close releases the database directory lock acquired by Open and closes the data files kept open for reading
+func (fs *Disk) Close(path string) error {
+	hErr := fs.handles.invalidateDir(path, nil)
+
+	fs.m.Lock()
+	defer fs.m.Unlock()
+
+	f, ok := fs.locks[path]
+	if !ok {
+		return hErr
+	}
+
+	delete(fs.locks, path)
+
+	err := unlockFile(f)
+	if err != nil {
+		_ = f.Close()
+
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	return hErr
+}
+
+func (fs *Disk) lock(path string) error {
+	fs.m.Lock()
+	defer fs.m.Unlock()
+
+	if fs.locks == nil {
+		fs.locks = make(map[string]*os.File)
+	}
+
+	if _, ok := fs.locks[path]; ok {
+		return core.ErrDatabaseLocked
 	}
 
-	return fs.openFile(path, dataFile, len(entries))
+	f, err := os.OpenFile(gopath.Join(path, lockFileName), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+
+	err = lockFile(f)
+	if err != nil {
+		_ = f.Close()
+
+		return err
+	}
+
+	fs.locks[path] = f
+
+	return nil
 }
 
 // Rotate creates a new active data file and opens it
 func (fs *Disk) Rotate(path string) (core.File, error) {
-	entries, err := os.ReadDir(path)
+	files, err := fs.Files(path)
 	if err != nil {
 		return nil, err
 	}
 
-	return fs.openFile(path, "", len(entries))
+	return fs.openFile(path, "", nextFileID(files))
 }
 
-func (fs *Disk) openFile(path string, dataFile string, n int) (core.File, error) {
+func (fs *Disk) openFile(path string, dataFile string, id uint64) (core.File, error) {
 	if dataFile == "" {
-		dataFile = fmt.Sprintf("data_%d_%d.csk", n, time.Now().Unix())
+		dataFile = fmt.Sprintf("data_%d_%d%s", id, time.Now().Unix(), dataFileExt)
 	}
 
 	file, err := os.OpenFile(
@@ -119,13 +240,51 @@ func (fs *Disk) createDir(path string) error {
 	return nil
 }
 
-func (fs *Disk) Walk(path string, wf func(core.File) error) error {
-	return filepath.Walk(path, func(p string, info gofs.FileInfo, err error) error {
-		if info.IsDir() || gopath.Ext(p) != ".csk" {
-			return nil
+// Files lists names of all data files ordered from the oldest to the newest one
+func (fs *Disk) Files(path string) ([]string, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != dataFileExt {
+			continue
 		}
 
-		file, err := os.OpenFile(p, os.O_RDONLY, 0755)
+		files = append(files, dataFileName(entry.Name()))
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return dataFileLess(files[i], files[j])
+	})
+
+	// Data files removed in the meantime (eg. by another process) should not be kept open
+	keep := make(map[string]bool, len(files))
+
+	for _, name := range files {
+		keep[gopath.Join(path, name+dataFileExt)] = true
+	}
+
+	err = fs.handles.invalidateDir(path, keep)
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// Walk walks through all data files in order from the oldest to the newest one
+func (fs *Disk) Walk(path string, wf func(core.File) error) error {
+	files, err := fs.Files(path)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range files {
+		file, err := os.OpenFile(gopath.Join(path, name+dataFileExt), os.O_RDONLY, 0755)
 		if err != nil {
 			return err
 		}
@@ -140,20 +299,235 @@ func (fs *Disk) Walk(path string, wf func(core.File) error) error {
 			return err
 		}
 
-		return file.Close()
-	})
+		err = file.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
+// ReadFileAt reads a chunk of the named data file at the given offset.
+// Data files are kept open for subsequent reads (up to the configured number of read handles)
 func (fs *Disk) ReadFileAt(path string, file string, b []byte, o int64) (int, error) {
-	f, err := os.OpenFile(gopath.Join(path, fmt.Sprintf("%s%s", file, ".csk")), os.O_RDONLY, 0755)
+	h, err := fs.handles.acquire(gopath.Join(path, file+dataFileExt))
 	if err != nil {
 		return 0, err
 	}
 
-	n, err := f.ReadAt(b, o)
+	n, err := h.file.ReadAt(b, o)
+
+	rErr := fs.handles.release(h)
 	if err != nil {
 		return n, err
 	}
 
-	return n, f.Close()
+	return n, rErr
+}
+
+// CreateMergeFile creates a new data file which is ordered right before the active data file.
+// The file is kept hidden (with .merge extension) until it's committed with CommitMerge
+func (fs *Disk) CreateMergeFile(path string, active string) (core.File, error) {
+	ids := fileIDs(active)
+	if len(ids) == 0 || ids[0] == 0 {
+		return nil, fmt.Errorf("gocask: no data files precede active data file %s", active)
+	}
+
+	for {
+		name := fmt.Sprintf("data_%d_%d%s", ids[0]-1, time.Now().UnixNano(), dataFileExt)
+
+		file, err := os.OpenFile(
+			gopath.Join(path, name+mergeFileExt),
+			os.O_RDWR|os.O_CREATE|os.O_EXCL|os.O_APPEND,
+			0755,
+		)
+		if err != nil {
+			if errors.Is(err, os.ErrExist) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		return &DiskFile{file, 0}, nil
+	}
+}
+
+// CommitMerge makes merged data files visible by stripping their .merge extension
+func (fs *Disk) CommitMerge(path string, files []string) error {
+	for _, name := range files {
+		p := gopath.Join(path, name+dataFileExt)
+
+		err := fs.handles.invalidate(p)
+		if err != nil {
+			return err
+		}
+
+		err = os.Rename(p+mergeFileExt, p)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Remove removes named data file along with its hint file
+func (fs *Disk) Remove(path string, file string) error {
+	p := gopath.Join(path, file+dataFileExt)
+
+	err := fs.handles.invalidate(p)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(p)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(gopath.Join(path, file+hintFileExt))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// CreateHintFile creates a hint file for the named data file. The hint file is
+// written under a temporary name and it's renamed once it's closed
+func (fs *Disk) CreateHintFile(path string, dataFile string) (core.File, error) {
+	p := gopath.Join(path, dataFile+hintFileExt)
+
+	file, err := os.OpenFile(p+tmpFileExt, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hintFile{
+		DiskFile: DiskFile{file, 0},
+		path:     p,
+	}, nil
+}
+
+// OpenHintFile opens the hint file of the named data file for reading
+func (fs *Disk) OpenHintFile(path string, dataFile string) (core.File, error) {
+	file, err := os.OpenFile(gopath.Join(path, dataFile+hintFileExt), os.O_RDONLY, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DiskFile{file, 0}, nil
+}
+
+// removePending removes leftovers of interrupted merges, eg. uncommitted merge files
+// and hint files without data files
+func (fs *Disk) removePending(path string) error {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		name := entry.Name()
+
+		switch filepath.Ext(name) {
+		case mergeFileExt, tmpFileExt:
+		case hintFileExt:
+			_, err := os.Stat(gopath.Join(path, strings.TrimSuffix(name, hintFileExt)+dataFileExt))
+			if !errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+		default:
+			continue
+		}
+
+		err := os.Remove(gopath.Join(path, name))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type hintFile struct {
+	DiskFile
+
+	path string
+}
+
+// Close closes the hint file and makes it visible under its final name
+func (f *hintFile) Close() error {
+	err := f.DiskFile.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.File.Name(), f.path)
+}
+
+// dataFileName strips path and extension(s) from data file path
+func dataFileName(p string) string {
+	name := filepath.Base(p)
+
+	return strings.TrimSuffix(
+		strings.TrimSuffix(name, mergeFileExt),
+		dataFileExt,
+	)
+}
+
+// dataFileLess orders data files by the numbers embedded in their names
+// (eg. data_<id>_<timestamp>) falling back to lexicographical order
+func dataFileLess(a, b string) bool {
+	ai, bi := fileIDs(a), fileIDs(b)
+
+	for i := 0; i < len(ai) && i < len(bi); i++ {
+		if ai[i] != bi[i] {
+			return ai[i] < bi[i]
+		}
+	}
+
+	if len(ai) != len(bi) {
+		return len(ai) < len(bi)
+	}
+
+	return a < b
+}
+
+func fileIDs(name string) []uint64 {
+	var ids []uint64
+
+	fields := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsDigit(r)
+	})
+
+	for _, f := range fields {
+		id, err := strconv.ParseUint(f, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+func nextFileID(files []string) uint64 {
+	var next uint64
+
+	for _, f := range files {
+		ids := fileIDs(f)
+		if len(ids) > 0 && ids[0] >= next {
+			next = ids[0] + 1
+		}
+	}
+
+	return next
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"os"
 	"path"
+	"sync"
 	"testing"
 )
 
@@ -176,4 +177,215 @@ func TestReadFileAt_Should_Report_Nonexistent_File_Error(t *testing.T) {
 	_, err := disk.ReadFileAt("i-do-not", "exist", nil, 0)
 
 	assert.Error(t, err)
-}
\ No newline at end of file
+}
+
+func TestDiskFS_Should_List_Data_Files_In_Numerical_Order(t *testing.T) {
+	db, _ := os.MkdirTemp("", "db0004")
+
+	defer os.RemoveAll(db)
+
+	for _, name := range []string{"data_10_5.csk", "data_2_9.csk", "data_2_7_1.csk", "data_2_7.csk", "foo.txt"} {
+		f, _ := os.Create(path.Join(db, name))
+		f.Close()
+	}
+
+	disk := fs.NewDisk()
+
+	files, err := disk.Files(db)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"data_2_7", "data_2_7_1", "data_2_9", "data_10_5"}, files)
+
+	file, err := disk.Rotate(db)
+
+	assert.NoError(t, err)
+	assert.Regexp(t, "^data_11_", file.Name())
+}
+
+func TestDiskFS_Should_Commit_Merge_File_Before_Active_Data_File(t *testing.T) {
+	disk := fs.NewDisk()
+
+	db, _ := os.MkdirTemp("", "db0005")
+
+	defer os.RemoveAll(db)
+
+	old, _ := disk.Open(db)
+	active, _ := disk.Rotate(db)
+
+	merged, err := disk.CreateMergeFile(db, active.Name())
+
+	assert.NoError(t, err)
+
+	files, _ := disk.Files(db)
+
+	assert.Equal(t, []string{old.Name(), active.Name()}, files)
+
+	err = disk.CommitMerge(db, []string{merged.Name()})
+
+	assert.NoError(t, err)
+
+	err = disk.Remove(db, old.Name())
+
+	assert.NoError(t, err)
+
+	files, _ = disk.Files(db)
+
+	assert.Equal(t, []string{merged.Name(), active.Name()}, files)
+}
+
+func TestDiskFS_Should_Discard_Uncommitted_Merge_Files_On_Open(t *testing.T) {
+	disk := fs.NewDisk()
+
+	db, _ := os.MkdirTemp("", "db0006")
+
+	defer os.RemoveAll(db)
+
+	_, _ = disk.Open(db)
+	active, _ := disk.Rotate(db)
+
+	merged, _ := disk.CreateMergeFile(db, active.Name())
+
+	_ = disk.Close(db)
+
+	_, err := disk.Open(db)
+
+	assert.NoError(t, err)
+	assert.NoFileExists(t, path.Join(db, merged.Name()+".csk.merge"))
+}
+
+func TestDiskFS_Hint_File_Should_Become_Visible_Once_Closed(t *testing.T) {
+	disk := fs.NewDisk()
+
+	db, _ := os.MkdirTemp("", "db0007")
+
+	defer os.RemoveAll(db)
+
+	file, _ := disk.Open(db)
+
+	hint, err := disk.CreateHintFile(db, file.Name())
+
+	assert.NoError(t, err)
+
+	_, err = hint.Write([]byte("hint"))
+
+	assert.NoError(t, err)
+
+	_, err = disk.OpenHintFile(db, file.Name())
+
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	assert.NoError(t, hint.Close())
+
+	hint, err = disk.OpenHintFile(db, file.Name())
+
+	assert.NoError(t, err)
+	assert.NoError(t, hint.Close())
+
+	assert.NoError(t, disk.Remove(db, file.Name()))
+	assert.NoFileExists(t, path.Join(db, file.Name()+".hint"))
+}
+
+func TestDiskFS_Should_Report_Locked_DB(t *testing.T) {
+	db, _ := os.MkdirTemp("", "db0008")
+
+	defer os.RemoveAll(db)
+
+	disk := fs.NewDisk()
+
+	_, err := disk.Open(db)
+
+	assert.NoError(t, err)
+
+	_, err = fs.NewDisk().Open(db)
+
+	assert.ErrorIs(t, err, core.ErrDatabaseLocked)
+
+	assert.NoError(t, disk.Close(db))
+
+	_, err = fs.NewDisk().Open(db)
+
+	assert.NoError(t, err)
+}
+
+func TestDiskFS_Should_Not_Read_Removed_Data_File_Through_Open_Handle(t *testing.T) {
+	db, _ := os.MkdirTemp("", "db0009")
+
+	defer os.RemoveAll(db)
+
+	p := path.Join(db, "data_1_1.csk")
+
+	assert.NoError(t, os.WriteFile(p, []byte("old"), 0755))
+
+	disk := fs.NewDisk()
+
+	b := make([]byte, 3)
+
+	_, err := disk.ReadFileAt(db, "data_1_1", b, 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("old"), b)
+
+	assert.NoError(t, disk.Remove(db, "data_1_1"))
+
+	_, err = disk.ReadFileAt(db, "data_1_1", b, 0)
+
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// Data file removed by another process is no longer read once data files are listed
+	assert.NoError(t, os.WriteFile(p, []byte("new"), 0755))
+
+	_, err = disk.ReadFileAt(db, "data_1_1", b, 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("new"), b)
+
+	assert.NoError(t, os.Remove(p))
+
+	_, err = disk.Files(db)
+
+	assert.NoError(t, err)
+
+	_, err = disk.ReadFileAt(db, "data_1_1", b, 0)
+
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestDiskFS_Should_Read_Concurrently_With_Evicted_Read_Handles(t *testing.T) {
+	db, _ := os.MkdirTemp("", "db0010")
+
+	defer os.RemoveAll(db)
+
+	files := []string{"data_1_1", "data_2_1", "data_3_1"}
+
+	for _, name := range files {
+		assert.NoError(t, os.WriteFile(path.Join(db, name+".csk"), []byte(name), 0755))
+	}
+
+	for _, handles := range []int{0, 1, 64} {
+		disk := fs.NewDisk().WithReadHandles(handles)
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+
+				for j := 0; j < 100; j++ {
+					name := files[(i+j)%len(files)]
+					b := make([]byte, len(name))
+
+					_, err := disk.ReadFileAt(db, name, b, 0)
+
+					assert.NoError(t, err)
+					assert.Equal(t, []byte(name), b)
+				}
+			}(i)
+		}
+
+		wg.Wait()
+
+		assert.NoError(t, disk.Close(db))
+	}
+}
@@ -0,0 +1,184 @@
+package fs
+
+import (
+	"container/list"
+	"io"
+	"os"
+	gopath "path"
+	"sync"
+)
+
+// defaultReadHandles is the number of data files kept open for reading by default
+const defaultReadHandles = 64
+
+// readerAtCloser represents a data file opened for reading
+type readerAtCloser interface {
+	io.ReaderAt
+	io.Closer
+}
+
+// handleCache keeps a bounded number of data files open for reading and closes the least recently used ones.
+// Handle which is evicted while it's being read from is closed once the read is done
+type handleCache struct {
+	m       sync.Mutex
+	max     int
+	lru     *list.List
+	handles map[string]*list.Element
+
+	// open opens the data file for reading (os.Open is used if nil)
+	open func(string) (readerAtCloser, error)
+}
+
+type handle struct {
+	path    string
+	file    readerAtCloser
+	refs    int
+	evicted bool
+}
+
+// acquire returns an open handle of the file at the given path which has to be released after reading
+func (c *handleCache) acquire(p string) (*handle, error) {
+	c.m.Lock()
+
+	if h, ok := c.cached(p); ok {
+		c.m.Unlock()
+
+		return h, nil
+	}
+
+	c.m.Unlock()
+
+	file, err := c.openFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	// The file may have been opened by a concurrent read in the meantime
+	if h, ok := c.cached(p); ok {
+		_ = file.Close()
+
+		return h, nil
+	}
+
+	h := &handle{
+		path: p,
+		file: file,
+		refs: 1,
+	}
+
+	if c.max <= 0 {
+		h.evicted = true
+
+		return h, nil
+	}
+
+	if c.handles == nil {
+		c.lru = list.New()
+		c.handles = make(map[string]*list.Element)
+	}
+
+	c.handles[p] = c.lru.PushFront(h)
+
+	for c.lru.Len() > c.max {
+		_ = c.evict(c.lru.Back())
+	}
+
+	return h, nil
+}
+
+func (c *handleCache) openFile(p string) (readerAtCloser, error) {
+	if c.open != nil {
+		return c.open(p)
+	}
+
+	return os.OpenFile(p, os.O_RDONLY, 0755)
+}
+
+func (c *handleCache) cached(p string) (*handle, bool) {
+	e, ok := c.handles[p]
+	if !ok {
+		return nil, false
+	}
+
+	c.lru.MoveToFront(e)
+
+	h := e.Value.(*handle)
+
+	h.refs++
+
+	return h, true
+}
+
+// release closes the handle if it was evicted while it was being read from
+func (c *handleCache) release(h *handle) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	h.refs--
+
+	if h.refs == 0 && h.evicted {
+		return h.file.Close()
+	}
+
+	return nil
+}
+
+func (c *handleCache) evict(e *list.Element) error {
+	h := c.lru.Remove(e).(*handle)
+
+	delete(c.handles, h.path)
+
+	h.evicted = true
+
+	if h.refs == 0 {
+		return h.file.Close()
+	}
+
+	return nil
+}
+
+// invalidate closes the handles of the files at the given paths
+func (c *handleCache) invalidate(paths ...string) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	var err error
+
+	for _, p := range paths {
+		e, ok := c.handles[p]
+		if !ok {
+			continue
+		}
+
+		eErr := c.evict(e)
+		if err == nil {
+			err = eErr
+		}
+	}
+
+	return err
+}
+
+// invalidateDir closes the handles of all files in the db path except for the named data files
+func (c *handleCache) invalidateDir(path string, keep map[string]bool) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	var err error
+
+	for p, e := range c.handles {
+		if gopath.Dir(p) != gopath.Clean(path) || keep[p] {
+			continue
+		}
+
+		eErr := c.evict(e)
+		if err == nil {
+			err = eErr
+		}
+	}
+
+	return err
+}
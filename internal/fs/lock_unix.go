@@ -0,0 +1,24 @@
+//go:build !windows
+// +build !windows
+
+package fs
+
+import (
+	"errors"
+	"github.com/aneshas/gocask/core"
+	"os"
+	"syscall"
+)
+
+func lockFile(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return core.ErrDatabaseLocked
+	}
+
+	return err
+}
+
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
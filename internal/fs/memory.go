@@ -2,14 +2,17 @@ package fs
 
 import (
 	"bytes"
+	"errors"
 	"github.com/aneshas/gocask/core"
 	"io"
+	gofs "io/fs"
 )
 
 type InMemoryFile struct {
 	name   string
 	reader io.Reader
 	f      func([]byte)
+	t      func(int64)
 }
 
 func (i *InMemoryFile) Read(p []byte) (n int, err error) {
@@ -34,6 +37,16 @@ func (i *InMemoryFile) Size() int64 {
 	return 0
 }
 
+func (i *InMemoryFile) Sync() error {
+	return nil
+}
+
+func (i *InMemoryFile) Truncate(size int64) error {
+	i.t(size)
+
+	return nil
+}
+
 type InMemory struct {
 	b           []byte
 	currentFile *InMemoryFile
@@ -50,11 +63,18 @@ func (i *InMemory) Open(_ string) (core.File, error) {
 		f: func(buf []byte) {
 			i.b = append(i.b, buf...)
 		},
+		t: func(size int64) {
+			i.b = i.b[:size]
+		},
 	}
 
 	return i.currentFile, nil
 }
 
+func (i *InMemory) Close(_ string) error {
+	return nil
+}
+
 func (i *InMemory) Rotate(_ string) (core.File, error) {
 	return i.currentFile, nil
 }
@@ -74,7 +94,42 @@ func (i *InMemory) Walk(_ string, f func(core.File) error) error {
 }
 
 func (i *InMemory) ReadFileAt(_ string, _ string, b []byte, offset int64) (int, error) {
-	copy(b, i.b[offset:])
+	if offset >= int64(len(i.b)) {
+		if len(b) == 0 {
+			return 0, nil
+		}
+
+		return 0, io.EOF
+	}
+
+	n := copy(b, i.b[offset:])
+	if n < len(b) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+func (i *InMemory) Files(_ string) ([]string, error) {
+	return []string{"data"}, nil
+}
+
+func (i *InMemory) CreateMergeFile(_ string, _ string) (core.File, error) {
+	return nil, errors.New("gocask: in memory db consists of a single data file and can not be merged")
+}
+
+func (i *InMemory) CommitMerge(_ string, _ []string) error {
+	return nil
+}
+
+func (i *InMemory) Remove(_ string, _ string) error {
+	return nil
+}
+
+func (i *InMemory) CreateHintFile(_ string, _ string) (core.File, error) {
+	return nil, errors.New("gocask: in memory db does not support hint files")
+}
 
-	return len(b), nil
+func (i *InMemory) OpenHintFile(_ string, _ string) (core.File, error) {
+	return nil, gofs.ErrNotExist
 }
@@ -5,6 +5,7 @@ import (
 	"github.com/aneshas/gocask/core"
 	"github.com/aneshas/gocask/internal/fs"
 	"github.com/stretchr/testify/assert"
+	"io"
 	"testing"
 )
 
@@ -92,4 +93,20 @@ func TestWalk_Should_Propagate_Error(t *testing.T) {
 	})
 
 	assert.ErrorIs(t, e, err)
-}
\ No newline at end of file
+}
+
+func TestReadFileAt_Should_Report_EOF_For_Short_Read(t *testing.T) {
+	mem := fs.NewInMemory()
+
+	f, _ := mem.Open("")
+
+	_, _ = f.Write([]byte("foo"))
+
+	b := make([]byte, 5)
+
+	n, err := mem.ReadFileAt("", "", b, 1)
+
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []byte("oo"), b[:n])
+}
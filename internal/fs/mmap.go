@@ -0,0 +1,213 @@
+package fs
+
+import (
+	"errors"
+	"github.com/aneshas/gocask/core"
+	"io"
+	gopath "path"
+	"sync"
+)
+
+// defaultMappings is the number of data files kept mapped by default
+const defaultMappings = 1024
+
+// NewMMap instantiates new disk based file system which reads immutable data files through memory mappings
+func NewMMap() *MMap {
+	return &MMap{
+		Disk: NewDisk(),
+		maps: handleCache{
+			max:  defaultMappings,
+			open: mapFile,
+		},
+		active: make(map[string]string),
+	}
+}
+
+// MMap represents disk based file system which maps immutable (rotated) data files read-only,
+// so that reading a value is a copy from the mapping instead of a syscall.
+// The active data file keeps being read with ReadAt since it's still being appended to
+type MMap struct {
+	*Disk
+
+	// maps keeps immutable data files mapped for reading by ReadFileAt
+	maps handleCache
+
+	m sync.Mutex
+
+	// active holds the path of the newest data file of each db path
+	active map[string]string
+}
+
+// WithMappings configures the max number of data files kept mapped (1024 by default).
+// Data files are mapped upon every read if n is not positive
+func (fs *MMap) WithMappings(n int) *MMap {
+	fs.maps.max = n
+
+	return fs
+}
+
+// Open opens a default data file for reading and creates it if it does not exist (see Disk.Open)
+func (fs *MMap) Open(path string) (core.File, error) {
+	file, err := fs.Disk.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fs.setActive(path, file.Name())
+
+	return file, nil
+}
+
+// Rotate creates a new active data file and opens it
+func (fs *MMap) Rotate(path string) (core.File, error) {
+	file, err := fs.Disk.Rotate(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fs.setActive(path, file.Name())
+
+	return file, nil
+}
+
+// Close releases the database directory lock acquired by Open and unmaps its data files
+func (fs *MMap) Close(path string) error {
+	mErr := fs.maps.invalidateDir(path, nil)
+
+	fs.m.Lock()
+	delete(fs.active, path)
+	fs.m.Unlock()
+
+	err := fs.Disk.Close(path)
+	if err != nil {
+		return err
+	}
+
+	return mErr
+}
+
+// Files lists names of all data files ordered from the oldest to the newest one.
+// The newest data file is considered active, so it's not mapped (it may be written to by another process)
+func (fs *MMap) Files(path string) ([]string, error) {
+	files, err := fs.Disk.Files(path)
+	if err != nil {
+		return nil, err
+	}
+
+	keep := make(map[string]bool, len(files))
+
+	for _, name := range files {
+		keep[gopath.Join(path, name+dataFileExt)] = true
+	}
+
+	err = fs.maps.invalidateDir(path, keep)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) > 0 {
+		fs.setActive(path, files[len(files)-1])
+	}
+
+	return files, nil
+}
+
+// ReadFileAt reads a chunk of the named data file at the given offset.
+// Immutable data files are copied from their mappings, the active one is read with ReadAt
+func (fs *MMap) ReadFileAt(path string, file string, b []byte, o int64) (int, error) {
+	if fs.isActive(path, file) {
+		return fs.Disk.ReadFileAt(path, file, b, o)
+	}
+
+	h, err := fs.maps.acquire(gopath.Join(path, file+dataFileExt))
+	if err != nil {
+		// Data file may not be mappable (eg. it does not fit in the address space)
+		return fs.Disk.ReadFileAt(path, file, b, o)
+	}
+
+	n, err := h.file.ReadAt(b, o)
+
+	rErr := fs.maps.release(h)
+	if err != nil {
+		return n, err
+	}
+
+	return n, rErr
+}
+
+// CommitMerge makes merged data files visible by stripping their .merge extension
+func (fs *MMap) CommitMerge(path string, files []string) error {
+	for _, name := range files {
+		err := fs.maps.invalidate(gopath.Join(path, name+dataFileExt))
+		if err != nil {
+			return err
+		}
+	}
+
+	return fs.Disk.CommitMerge(path, files)
+}
+
+// Remove unmaps and removes named data file along with its hint file
+func (fs *MMap) Remove(path string, file string) error {
+	err := fs.maps.invalidate(gopath.Join(path, file+dataFileExt))
+	if err != nil {
+		return err
+	}
+
+	return fs.Disk.Remove(path, file)
+}
+
+func (fs *MMap) setActive(path string, file string) {
+	fs.m.Lock()
+	defer fs.m.Unlock()
+
+	fs.active[path] = file
+}
+
+// isActive reports whether the named data file may still be appended to.
+// Data files of a path which was not listed or opened yet are treated as active
+func (fs *MMap) isActive(path string, file string) bool {
+	fs.m.Lock()
+	defer fs.m.Unlock()
+
+	active, ok := fs.active[path]
+
+	return !ok || active == file
+}
+
+// mapping is a data file mapped read-only
+type mapping struct {
+	data  []byte
+	unmap func([]byte) error
+}
+
+// ReadAt copies a chunk of the mapped data file at the given offset
+func (m *mapping) ReadAt(b []byte, o int64) (int, error) {
+	if o < 0 {
+		return 0, errors.New("gocask: negative offset")
+	}
+
+	if o >= int64(len(m.data)) {
+		return 0, io.EOF
+	}
+
+	n := copy(b, m.data[o:])
+	if n < len(b) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// Close unmaps the data file
+func (m *mapping) Close() error {
+	if m.data == nil {
+		return nil
+	}
+
+	data := m.data
+
+	m.data = nil
+
+	return m.unmap(data)
+}
@@ -0,0 +1,133 @@
+package fs_test
+
+import (
+	"github.com/aneshas/gocask/internal/fs"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path"
+	"sync"
+	"testing"
+)
+
+func TestMMapFS_Should_Read_Immutable_And_Active_Data_Files(t *testing.T) {
+	db, _ := os.MkdirTemp("", "db0011")
+
+	defer os.RemoveAll(db)
+
+	disk := fs.NewMMap()
+
+	defer disk.Close(db)
+
+	file, err := disk.Open(db)
+
+	assert.NoError(t, err)
+
+	_, err = file.Write([]byte("immutable"))
+
+	assert.NoError(t, err)
+
+	active, err := disk.Rotate(db)
+
+	assert.NoError(t, err)
+
+	b := make([]byte, 9)
+
+	_, err = disk.ReadFileAt(db, file.Name(), b, 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("immutable"), b)
+
+	n, err := disk.ReadFileAt(db, file.Name(), b, 5)
+
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, 4, n)
+
+	// Active data file is appended to after it was read from
+	for _, chunk := range []string{"foo", "bar"} {
+		_, err = active.Write([]byte(chunk))
+
+		assert.NoError(t, err)
+
+		b := make([]byte, 3)
+
+		_, err = disk.ReadFileAt(db, active.Name(), b, active.Size()-3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(chunk), b)
+	}
+}
+
+func TestMMapFS_Should_Not_Read_Removed_Data_File_Through_Mapping(t *testing.T) {
+	db, _ := os.MkdirTemp("", "db0012")
+
+	defer os.RemoveAll(db)
+
+	for _, name := range []string{"data_1_1", "data_2_1"} {
+		assert.NoError(t, os.WriteFile(path.Join(db, name+".csk"), []byte("old"), 0755))
+	}
+
+	disk := fs.NewMMap()
+
+	files, err := disk.Files(db)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"data_1_1", "data_2_1"}, files)
+
+	b := make([]byte, 3)
+
+	_, err = disk.ReadFileAt(db, "data_1_1", b, 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("old"), b)
+
+	assert.NoError(t, disk.Remove(db, "data_1_1"))
+
+	_, err = disk.ReadFileAt(db, "data_1_1", b, 0)
+
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestMMapFS_Should_Read_Concurrently_With_Evicted_Mappings(t *testing.T) {
+	db, _ := os.MkdirTemp("", "db0013")
+
+	defer os.RemoveAll(db)
+
+	files := []string{"data_1_1", "data_2_1", "data_3_1", "data_4_1"}
+
+	for _, name := range files {
+		assert.NoError(t, os.WriteFile(path.Join(db, name+".csk"), []byte(name), 0755))
+	}
+
+	for _, mappings := range []int{0, 1, 1024} {
+		disk := fs.NewMMap().WithMappings(mappings)
+
+		_, err := disk.Files(db)
+
+		assert.NoError(t, err)
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+
+				for j := 0; j < 100; j++ {
+					name := files[(i+j)%len(files)]
+					b := make([]byte, len(name))
+
+					_, err := disk.ReadFileAt(db, name, b, 0)
+
+					assert.NoError(t, err)
+					assert.Equal(t, []byte(name), b)
+				}
+			}(i)
+		}
+
+		wg.Wait()
+
+		assert.NoError(t, disk.Close(db))
+	}
+}
@@ -0,0 +1,43 @@
+//go:build !windows
+// +build !windows
+
+package fs
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+func mapFile(p string) (readerAtCloser, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	size := info.Size()
+	if size == 0 {
+		return &mapping{}, nil
+	}
+
+	if int64(int(size)) != size {
+		return nil, fmt.Errorf("gocask: data file %s is too large to be mapped", p)
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mapping{
+		data:  data,
+		unmap: syscall.Munmap,
+	}, nil
+}
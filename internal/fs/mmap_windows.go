@@ -0,0 +1,63 @@
+//go:build windows
+// +build windows
+
+package fs
+
+import (
+	"fmt"
+	"golang.org/x/sys/windows"
+	"os"
+	"reflect"
+	"unsafe"
+)
+
+func mapFile(p string) (readerAtCloser, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	size := info.Size()
+	if size == 0 {
+		return &mapping{}, nil
+	}
+
+	if int64(int(size)) != size {
+		return nil, fmt.Errorf("gocask: data file %s is too large to be mapped", p)
+	}
+
+	h, err := windows.CreateFileMapping(windows.Handle(f.Fd()), nil, windows.PAGE_READONLY, 0, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// The view keeps the mapping object alive on its own
+	defer windows.CloseHandle(h)
+
+	addr, err := windows.MapViewOfFile(h, windows.FILE_MAP_READ, 0, 0, uintptr(size))
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+
+	hdr := (*reflect.SliceHeader)(unsafe.Pointer(&data))
+
+	hdr.Data = addr
+	hdr.Len = int(size)
+	hdr.Cap = int(size)
+
+	return &mapping{
+		data: data,
+		unmap: func(data []byte) error {
+			return windows.UnmapViewOfFile(uintptr(unsafe.Pointer(&data[0])))
+		},
+	}, nil
+}
@@ -216,19 +216,193 @@ func (x *PutRequest) GetValue() []byte {
 	return nil
 }
 
+type CompareAndSwapRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Key      []byte `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Expected []byte `protobuf:"bytes,2,opt,name=expected,proto3" json:"expected,omitempty"`
+	Value    []byte `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (x *CompareAndSwapRequest) Reset() {
+	*x = CompareAndSwapRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_gocask_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CompareAndSwapRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompareAndSwapRequest) ProtoMessage() {}
+
+func (x *CompareAndSwapRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_gocask_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompareAndSwapRequest.ProtoReflect.Descriptor instead.
+func (*CompareAndSwapRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_gocask_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CompareAndSwapRequest) GetKey() []byte {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *CompareAndSwapRequest) GetExpected() []byte {
+	if x != nil {
+		return x.Expected
+	}
+	return nil
+}
+
+func (x *CompareAndSwapRequest) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+type PutIfVersionRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Key     []byte `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value   []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Version uint64 `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+}
+
+func (x *PutIfVersionRequest) Reset() {
+	*x = PutIfVersionRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_gocask_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PutIfVersionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PutIfVersionRequest) ProtoMessage() {}
+
+func (x *PutIfVersionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_gocask_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PutIfVersionRequest.ProtoReflect.Descriptor instead.
+func (*PutIfVersionRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_gocask_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *PutIfVersionRequest) GetKey() []byte {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *PutIfVersionRequest) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *PutIfVersionRequest) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type PutResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Version uint64 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+}
+
+func (x *PutResponse) Reset() {
+	*x = PutResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_gocask_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PutResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PutResponse) ProtoMessage() {}
+
+func (x *PutResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_gocask_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PutResponse.ProtoReflect.Descriptor instead.
+func (*PutResponse) Descriptor() ([]byte, []int) {
+	return file_rpc_gocask_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *PutResponse) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
 type Entry struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Key   []byte `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
-	Value []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Key     []byte `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value   []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Version uint64 `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
 }
 
 func (x *Entry) Reset() {
 	*x = Entry{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_rpc_gocask_proto_msgTypes[4]
+		mi := &file_rpc_gocask_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -241,7 +415,7 @@ func (x *Entry) String() string {
 func (*Entry) ProtoMessage() {}
 
 func (x *Entry) ProtoReflect() protoreflect.Message {
-	mi := &file_rpc_gocask_proto_msgTypes[4]
+	mi := &file_rpc_gocask_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -254,7 +428,7 @@ func (x *Entry) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Entry.ProtoReflect.Descriptor instead.
 func (*Entry) Descriptor() ([]byte, []int) {
-	return file_rpc_gocask_proto_rawDescGZIP(), []int{4}
+	return file_rpc_gocask_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *Entry) GetKey() []byte {
@@ -271,6 +445,13 @@ func (x *Entry) GetValue() []byte {
 	return nil
 }
 
+func (x *Entry) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
 type Empty struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -280,7 +461,7 @@ type Empty struct {
 func (x *Empty) Reset() {
 	*x = Empty{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_rpc_gocask_proto_msgTypes[5]
+		mi := &file_rpc_gocask_proto_msgTypes[8]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -293,7 +474,7 @@ func (x *Empty) String() string {
 func (*Empty) ProtoMessage() {}
 
 func (x *Empty) ProtoReflect() protoreflect.Message {
-	mi := &file_rpc_gocask_proto_msgTypes[5]
+	mi := &file_rpc_gocask_proto_msgTypes[8]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -306,7 +487,7 @@ func (x *Empty) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Empty.ProtoReflect.Descriptor instead.
 func (*Empty) Descriptor() ([]byte, []int) {
-	return file_rpc_gocask_proto_rawDescGZIP(), []int{5}
+	return file_rpc_gocask_proto_rawDescGZIP(), []int{8}
 }
 
 var File_rpc_gocask_proto protoreflect.FileDescriptor
@@ -324,35 +505,69 @@ var file_rpc_gocask_proto_rawDesc = []byte{
 	0x0c, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x22, 0x34, 0x0a, 0x0a, 0x50, 0x75, 0x74, 0x52, 0x65, 0x71,
 	0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28,
 	0x0c, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18,
-	0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x2f, 0x0a, 0x05,
-	0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01,
-	0x28, 0x0c, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
-	0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x07, 0x0a,
-	0x05, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x32, 0xd1, 0x02, 0x0a, 0x06, 0x47, 0x6f, 0x43, 0x61, 0x73,
-	0x6b, 0x12, 0x4e, 0x0a, 0x03, 0x50, 0x75, 0x74, 0x12, 0x25, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x5b, 0x0a, 0x15,
+	0x43, 0x6f, 0x6d, 0x70, 0x61, 0x72, 0x65, 0x41, 0x6e, 0x64, 0x53, 0x77, 0x61, 0x70, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x1a, 0x0a, 0x08, 0x65, 0x78, 0x70, 0x65, 0x63,
+	0x74, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x65, 0x78, 0x70, 0x65, 0x63,
+	0x74, 0x65, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x57, 0x0a, 0x13, 0x50, 0x75, 0x74,
+	0x49, 0x66, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x03, 0x6b,
+	0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69,
+	0x6f, 0x6e, 0x22, 0x27, 0x0a, 0x0b, 0x50, 0x75, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x49, 0x0a, 0x05, 0x45,
+	0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x18, 0x0a, 0x07,
+	0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x76,
+	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x07, 0x0a, 0x05, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x32,
+	0x83, 0x05, 0x0a, 0x06, 0x47, 0x6f, 0x43, 0x61, 0x73, 0x6b, 0x12, 0x4e, 0x0a, 0x03, 0x50, 0x75,
+	0x74, 0x12, 0x25, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x61,
+	0x6e, 0x65, 0x73, 0x68, 0x61, 0x73, 0x2e, 0x67, 0x6f, 0x63, 0x61, 0x73, 0x6b, 0x2e, 0x50, 0x75,
+	0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x20, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75,
 	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x61, 0x6e, 0x65, 0x73, 0x68, 0x61, 0x73, 0x2e, 0x67, 0x6f,
-	0x63, 0x61, 0x73, 0x6b, 0x2e, 0x50, 0x75, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
-	0x20, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x61, 0x6e, 0x65,
-	0x73, 0x68, 0x61, 0x73, 0x2e, 0x67, 0x6f, 0x63, 0x61, 0x73, 0x6b, 0x2e, 0x45, 0x6d, 0x70, 0x74,
-	0x79, 0x12, 0x4e, 0x0a, 0x03, 0x47, 0x65, 0x74, 0x12, 0x25, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x63, 0x61, 0x73, 0x6b, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x12, 0x4e, 0x0a, 0x03, 0x47, 0x65,
+	0x74, 0x12, 0x25, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x61,
+	0x6e, 0x65, 0x73, 0x68, 0x61, 0x73, 0x2e, 0x67, 0x6f, 0x63, 0x61, 0x73, 0x6b, 0x2e, 0x47, 0x65,
+	0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x20, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75,
 	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x61, 0x6e, 0x65, 0x73, 0x68, 0x61, 0x73, 0x2e, 0x67, 0x6f,
-	0x63, 0x61, 0x73, 0x6b, 0x2e, 0x47, 0x65, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
-	0x20, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x61, 0x6e, 0x65,
-	0x73, 0x68, 0x61, 0x73, 0x2e, 0x67, 0x6f, 0x63, 0x61, 0x73, 0x6b, 0x2e, 0x45, 0x6e, 0x74, 0x72,
-	0x79, 0x12, 0x54, 0x0a, 0x06, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x12, 0x28, 0x2e, 0x67, 0x69,
-	0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x61, 0x6e, 0x65, 0x73, 0x68, 0x61, 0x73,
-	0x2e, 0x67, 0x6f, 0x63, 0x61, 0x73, 0x6b, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x52, 0x65,
-	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x20, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63,
+	0x63, 0x61, 0x73, 0x6b, 0x2e, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x54, 0x0a, 0x06, 0x44, 0x65,
+	0x6c, 0x65, 0x74, 0x65, 0x12, 0x28, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f,
+	0x6d, 0x2e, 0x61, 0x6e, 0x65, 0x73, 0x68, 0x61, 0x73, 0x2e, 0x67, 0x6f, 0x63, 0x61, 0x73, 0x6b,
+	0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x20,
+	0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x61, 0x6e, 0x65, 0x73,
+	0x68, 0x61, 0x73, 0x2e, 0x67, 0x6f, 0x63, 0x61, 0x73, 0x6b, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79,
+	0x12, 0x51, 0x0a, 0x04, 0x4b, 0x65, 0x79, 0x73, 0x12, 0x20, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x61, 0x6e, 0x65, 0x73, 0x68, 0x61, 0x73, 0x2e, 0x67, 0x6f,
+	0x63, 0x61, 0x73, 0x6b, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x27, 0x2e, 0x67, 0x69, 0x74,
+	0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x61, 0x6e, 0x65, 0x73, 0x68, 0x61, 0x73, 0x2e,
+	0x67, 0x6f, 0x63, 0x61, 0x73, 0x6b, 0x2e, 0x4b, 0x65, 0x79, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x6a, 0x0a, 0x0e, 0x43, 0x6f, 0x6d, 0x70, 0x61, 0x72, 0x65, 0x41, 0x6e,
+	0x64, 0x53, 0x77, 0x61, 0x70, 0x12, 0x30, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63,
+	0x6f, 0x6d, 0x2e, 0x61, 0x6e, 0x65, 0x73, 0x68, 0x61, 0x73, 0x2e, 0x67, 0x6f, 0x63, 0x61, 0x73,
+	0x6b, 0x2e, 0x43, 0x6f, 0x6d, 0x70, 0x61, 0x72, 0x65, 0x41, 0x6e, 0x64, 0x53, 0x77, 0x61, 0x70,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x26, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
+	0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x61, 0x6e, 0x65, 0x73, 0x68, 0x61, 0x73, 0x2e, 0x67, 0x6f, 0x63,
+	0x61, 0x73, 0x6b, 0x2e, 0x50, 0x75, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x5c, 0x0a, 0x0b, 0x50, 0x75, 0x74, 0x49, 0x66, 0x41, 0x62, 0x73, 0x65, 0x6e, 0x74, 0x12, 0x25,
+	0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x61, 0x6e, 0x65, 0x73,
+	0x68, 0x61, 0x73, 0x2e, 0x67, 0x6f, 0x63, 0x61, 0x73, 0x6b, 0x2e, 0x50, 0x75, 0x74, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x26, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63,
 	0x6f, 0x6d, 0x2e, 0x61, 0x6e, 0x65, 0x73, 0x68, 0x61, 0x73, 0x2e, 0x67, 0x6f, 0x63, 0x61, 0x73,
-	0x6b, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x12, 0x51, 0x0a, 0x04, 0x4b, 0x65, 0x79, 0x73, 0x12,
-	0x20, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x61, 0x6e, 0x65,
-	0x73, 0x68, 0x61, 0x73, 0x2e, 0x67, 0x6f, 0x63, 0x61, 0x73, 0x6b, 0x2e, 0x45, 0x6d, 0x70, 0x74,
-	0x79, 0x1a, 0x27, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x61,
-	0x6e, 0x65, 0x73, 0x68, 0x61, 0x73, 0x2e, 0x67, 0x6f, 0x63, 0x61, 0x73, 0x6b, 0x2e, 0x4b, 0x65,
-	0x79, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x1f, 0x5a, 0x1d, 0x67, 0x69,
-	0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x61, 0x6e, 0x65, 0x73, 0x68, 0x61, 0x73,
-	0x2f, 0x67, 0x6f, 0x63, 0x61, 0x73, 0x6b, 0x2f, 0x72, 0x70, 0x63, 0x62, 0x06, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x33,
+	0x6b, 0x2e, 0x50, 0x75, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x66, 0x0a,
+	0x0c, 0x50, 0x75, 0x74, 0x49, 0x66, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x2e, 0x2e,
+	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x61, 0x6e, 0x65, 0x73, 0x68,
+	0x61, 0x73, 0x2e, 0x67, 0x6f, 0x63, 0x61, 0x73, 0x6b, 0x2e, 0x50, 0x75, 0x74, 0x49, 0x66, 0x56,
+	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x26, 0x2e,
+	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x61, 0x6e, 0x65, 0x73, 0x68,
+	0x61, 0x73, 0x2e, 0x67, 0x6f, 0x63, 0x61, 0x73, 0x6b, 0x2e, 0x50, 0x75, 0x74, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x1f, 0x5a, 0x1d, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e,
+	0x63, 0x6f, 0x6d, 0x2f, 0x61, 0x6e, 0x65, 0x73, 0x68, 0x61, 0x73, 0x2f, 0x67, 0x6f, 0x63, 0x61,
+	0x73, 0x6b, 0x2f, 0x72, 0x70, 0x63, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -367,26 +582,35 @@ func file_rpc_gocask_proto_rawDescGZIP() []byte {
 	return file_rpc_gocask_proto_rawDescData
 }
 
-var file_rpc_gocask_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_rpc_gocask_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
 var file_rpc_gocask_proto_goTypes = []interface{}{
-	(*KeysResponse)(nil),  // 0: github.com.aneshas.gocask.KeysResponse
-	(*GetRequest)(nil),    // 1: github.com.aneshas.gocask.GetRequest
-	(*DeleteRequest)(nil), // 2: github.com.aneshas.gocask.DeleteRequest
-	(*PutRequest)(nil),    // 3: github.com.aneshas.gocask.PutRequest
-	(*Entry)(nil),         // 4: github.com.aneshas.gocask.Entry
-	(*Empty)(nil),         // 5: github.com.aneshas.gocask.Empty
+	(*KeysResponse)(nil),          // 0: github.com.aneshas.gocask.KeysResponse
+	(*GetRequest)(nil),            // 1: github.com.aneshas.gocask.GetRequest
+	(*DeleteRequest)(nil),         // 2: github.com.aneshas.gocask.DeleteRequest
+	(*PutRequest)(nil),            // 3: github.com.aneshas.gocask.PutRequest
+	(*CompareAndSwapRequest)(nil), // 4: github.com.aneshas.gocask.CompareAndSwapRequest
+	(*PutIfVersionRequest)(nil),   // 5: github.com.aneshas.gocask.PutIfVersionRequest
+	(*PutResponse)(nil),           // 6: github.com.aneshas.gocask.PutResponse
+	(*Entry)(nil),                 // 7: github.com.aneshas.gocask.Entry
+	(*Empty)(nil),                 // 8: github.com.aneshas.gocask.Empty
 }
 var file_rpc_gocask_proto_depIdxs = []int32{
 	3, // 0: github.com.aneshas.gocask.GoCask.Put:input_type -> github.com.aneshas.gocask.PutRequest
 	1, // 1: github.com.aneshas.gocask.GoCask.Get:input_type -> github.com.aneshas.gocask.GetRequest
 	2, // 2: github.com.aneshas.gocask.GoCask.Delete:input_type -> github.com.aneshas.gocask.DeleteRequest
-	5, // 3: github.com.aneshas.gocask.GoCask.Keys:input_type -> github.com.aneshas.gocask.Empty
-	5, // 4: github.com.aneshas.gocask.GoCask.Put:output_type -> github.com.aneshas.gocask.Empty
-	4, // 5: github.com.aneshas.gocask.GoCask.Get:output_type -> github.com.aneshas.gocask.Entry
-	5, // 6: github.com.aneshas.gocask.GoCask.Delete:output_type -> github.com.aneshas.gocask.Empty
-	0, // 7: github.com.aneshas.gocask.GoCask.Keys:output_type -> github.com.aneshas.gocask.KeysResponse
-	4, // [4:8] is the sub-list for method output_type
-	0, // [0:4] is the sub-list for method input_type
+	8, // 3: github.com.aneshas.gocask.GoCask.Keys:input_type -> github.com.aneshas.gocask.Empty
+	4, // 4: github.com.aneshas.gocask.GoCask.CompareAndSwap:input_type -> github.com.aneshas.gocask.CompareAndSwapRequest
+	3, // 5: github.com.aneshas.gocask.GoCask.PutIfAbsent:input_type -> github.com.aneshas.gocask.PutRequest
+	5, // 6: github.com.aneshas.gocask.GoCask.PutIfVersion:input_type -> github.com.aneshas.gocask.PutIfVersionRequest
+	8, // 7: github.com.aneshas.gocask.GoCask.Put:output_type -> github.com.aneshas.gocask.Empty
+	7, // 8: github.com.aneshas.gocask.GoCask.Get:output_type -> github.com.aneshas.gocask.Entry
+	8, // 9: github.com.aneshas.gocask.GoCask.Delete:output_type -> github.com.aneshas.gocask.Empty
+	0, // 10: github.com.aneshas.gocask.GoCask.Keys:output_type -> github.com.aneshas.gocask.KeysResponse
+	6, // 11: github.com.aneshas.gocask.GoCask.CompareAndSwap:output_type -> github.com.aneshas.gocask.PutResponse
+	6, // 12: github.com.aneshas.gocask.GoCask.PutIfAbsent:output_type -> github.com.aneshas.gocask.PutResponse
+	6, // 13: github.com.aneshas.gocask.GoCask.PutIfVersion:output_type -> github.com.aneshas.gocask.PutResponse
+	7, // [7:14] is the sub-list for method output_type
+	0, // [0:7] is the sub-list for method input_type
 	0, // [0:0] is the sub-list for extension type_name
 	0, // [0:0] is the sub-list for extension extendee
 	0, // [0:0] is the sub-list for field type_name
@@ -447,7 +671,7 @@ func file_rpc_gocask_proto_init() {
 			}
 		}
 		file_rpc_gocask_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Entry); i {
+			switch v := v.(*CompareAndSwapRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -459,6 +683,42 @@ func file_rpc_gocask_proto_init() {
 			}
 		}
 		file_rpc_gocask_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PutIfVersionRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rpc_gocask_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PutResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rpc_gocask_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Entry); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rpc_gocask_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*Empty); i {
 			case 0:
 				return &v.state
@@ -477,7 +737,7 @@ func file_rpc_gocask_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_rpc_gocask_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   9,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
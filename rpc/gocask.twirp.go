@@ -40,6 +40,12 @@ type GoCask interface {
 	Delete(context.Context, *DeleteRequest) (*Empty, error)
 
 	Keys(context.Context, *Empty) (*KeysResponse, error)
+
+	CompareAndSwap(context.Context, *CompareAndSwapRequest) (*PutResponse, error)
+
+	PutIfAbsent(context.Context, *PutRequest) (*PutResponse, error)
+
+	PutIfVersion(context.Context, *PutIfVersionRequest) (*PutResponse, error)
 }
 
 // ======================
@@ -48,7 +54,7 @@ type GoCask interface {
 
 type goCaskProtobufClient struct {
 	client      HTTPClient
-	urls        [4]string
+	urls        [7]string
 	interceptor twirp.Interceptor
 	opts        twirp.ClientOptions
 }
@@ -76,11 +82,14 @@ func NewGoCaskProtobufClient(baseURL string, client HTTPClient, opts ...twirp.Cl
 	// Build method URLs: <baseURL>[<prefix>]/<package>.<Service>/<Method>
 	serviceURL := sanitizeBaseURL(baseURL)
 	serviceURL += baseServicePath(pathPrefix, "github.com.aneshas.gocask", "GoCask")
-	urls := [4]string{
+	urls := [7]string{
 		serviceURL + "Put",
 		serviceURL + "Get",
 		serviceURL + "Delete",
 		serviceURL + "Keys",
+		serviceURL + "CompareAndSwap",
+		serviceURL + "PutIfAbsent",
+		serviceURL + "PutIfVersion",
 	}
 
 	return &goCaskProtobufClient{
@@ -275,13 +284,151 @@ func (c *goCaskProtobufClient) callKeys(ctx context.Context, in *Empty) (*KeysRe
 	return out, nil
 }
 
+func (c *goCaskProtobufClient) CompareAndSwap(ctx context.Context, in *CompareAndSwapRequest) (*PutResponse, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "github.com.aneshas.gocask")
+	ctx = ctxsetters.WithServiceName(ctx, "GoCask")
+	ctx = ctxsetters.WithMethodName(ctx, "CompareAndSwap")
+	caller := c.callCompareAndSwap
+	if c.interceptor != nil {
+		caller = func(ctx context.Context, req *CompareAndSwapRequest) (*PutResponse, error) {
+			resp, err := c.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*CompareAndSwapRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*CompareAndSwapRequest) when calling interceptor")
+					}
+					return c.callCompareAndSwap(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*PutResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*PutResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+	return caller(ctx, in)
+}
+
+func (c *goCaskProtobufClient) callCompareAndSwap(ctx context.Context, in *CompareAndSwapRequest) (*PutResponse, error) {
+	out := new(PutResponse)
+	ctx, err := doProtobufRequest(ctx, c.client, c.opts.Hooks, c.urls[4], in, out)
+	if err != nil {
+		twerr, ok := err.(twirp.Error)
+		if !ok {
+			twerr = twirp.InternalErrorWith(err)
+		}
+		callClientError(ctx, c.opts.Hooks, twerr)
+		return nil, err
+	}
+
+	callClientResponseReceived(ctx, c.opts.Hooks)
+
+	return out, nil
+}
+
+func (c *goCaskProtobufClient) PutIfAbsent(ctx context.Context, in *PutRequest) (*PutResponse, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "github.com.aneshas.gocask")
+	ctx = ctxsetters.WithServiceName(ctx, "GoCask")
+	ctx = ctxsetters.WithMethodName(ctx, "PutIfAbsent")
+	caller := c.callPutIfAbsent
+	if c.interceptor != nil {
+		caller = func(ctx context.Context, req *PutRequest) (*PutResponse, error) {
+			resp, err := c.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*PutRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*PutRequest) when calling interceptor")
+					}
+					return c.callPutIfAbsent(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*PutResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*PutResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+	return caller(ctx, in)
+}
+
+func (c *goCaskProtobufClient) callPutIfAbsent(ctx context.Context, in *PutRequest) (*PutResponse, error) {
+	out := new(PutResponse)
+	ctx, err := doProtobufRequest(ctx, c.client, c.opts.Hooks, c.urls[5], in, out)
+	if err != nil {
+		twerr, ok := err.(twirp.Error)
+		if !ok {
+			twerr = twirp.InternalErrorWith(err)
+		}
+		callClientError(ctx, c.opts.Hooks, twerr)
+		return nil, err
+	}
+
+	callClientResponseReceived(ctx, c.opts.Hooks)
+
+	return out, nil
+}
+
+func (c *goCaskProtobufClient) PutIfVersion(ctx context.Context, in *PutIfVersionRequest) (*PutResponse, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "github.com.aneshas.gocask")
+	ctx = ctxsetters.WithServiceName(ctx, "GoCask")
+	ctx = ctxsetters.WithMethodName(ctx, "PutIfVersion")
+	caller := c.callPutIfVersion
+	if c.interceptor != nil {
+		caller = func(ctx context.Context, req *PutIfVersionRequest) (*PutResponse, error) {
+			resp, err := c.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*PutIfVersionRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*PutIfVersionRequest) when calling interceptor")
+					}
+					return c.callPutIfVersion(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*PutResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*PutResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+	return caller(ctx, in)
+}
+
+func (c *goCaskProtobufClient) callPutIfVersion(ctx context.Context, in *PutIfVersionRequest) (*PutResponse, error) {
+	out := new(PutResponse)
+	ctx, err := doProtobufRequest(ctx, c.client, c.opts.Hooks, c.urls[6], in, out)
+	if err != nil {
+		twerr, ok := err.(twirp.Error)
+		if !ok {
+			twerr = twirp.InternalErrorWith(err)
+		}
+		callClientError(ctx, c.opts.Hooks, twerr)
+		return nil, err
+	}
+
+	callClientResponseReceived(ctx, c.opts.Hooks)
+
+	return out, nil
+}
+
 // ==================
 // GoCask JSON Client
 // ==================
 
 type goCaskJSONClient struct {
 	client      HTTPClient
-	urls        [4]string
+	urls        [7]string
 	interceptor twirp.Interceptor
 	opts        twirp.ClientOptions
 }
@@ -309,11 +456,14 @@ func NewGoCaskJSONClient(baseURL string, client HTTPClient, opts ...twirp.Client
 	// Build method URLs: <baseURL>[<prefix>]/<package>.<Service>/<Method>
 	serviceURL := sanitizeBaseURL(baseURL)
 	serviceURL += baseServicePath(pathPrefix, "github.com.aneshas.gocask", "GoCask")
-	urls := [4]string{
+	urls := [7]string{
 		serviceURL + "Put",
 		serviceURL + "Get",
 		serviceURL + "Delete",
 		serviceURL + "Keys",
+		serviceURL + "CompareAndSwap",
+		serviceURL + "PutIfAbsent",
+		serviceURL + "PutIfVersion",
 	}
 
 	return &goCaskJSONClient{
@@ -508,6 +658,144 @@ func (c *goCaskJSONClient) callKeys(ctx context.Context, in *Empty) (*KeysRespon
 	return out, nil
 }
 
+func (c *goCaskJSONClient) CompareAndSwap(ctx context.Context, in *CompareAndSwapRequest) (*PutResponse, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "github.com.aneshas.gocask")
+	ctx = ctxsetters.WithServiceName(ctx, "GoCask")
+	ctx = ctxsetters.WithMethodName(ctx, "CompareAndSwap")
+	caller := c.callCompareAndSwap
+	if c.interceptor != nil {
+		caller = func(ctx context.Context, req *CompareAndSwapRequest) (*PutResponse, error) {
+			resp, err := c.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*CompareAndSwapRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*CompareAndSwapRequest) when calling interceptor")
+					}
+					return c.callCompareAndSwap(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*PutResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*PutResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+	return caller(ctx, in)
+}
+
+func (c *goCaskJSONClient) callCompareAndSwap(ctx context.Context, in *CompareAndSwapRequest) (*PutResponse, error) {
+	out := new(PutResponse)
+	ctx, err := doJSONRequest(ctx, c.client, c.opts.Hooks, c.urls[4], in, out)
+	if err != nil {
+		twerr, ok := err.(twirp.Error)
+		if !ok {
+			twerr = twirp.InternalErrorWith(err)
+		}
+		callClientError(ctx, c.opts.Hooks, twerr)
+		return nil, err
+	}
+
+	callClientResponseReceived(ctx, c.opts.Hooks)
+
+	return out, nil
+}
+
+func (c *goCaskJSONClient) PutIfAbsent(ctx context.Context, in *PutRequest) (*PutResponse, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "github.com.aneshas.gocask")
+	ctx = ctxsetters.WithServiceName(ctx, "GoCask")
+	ctx = ctxsetters.WithMethodName(ctx, "PutIfAbsent")
+	caller := c.callPutIfAbsent
+	if c.interceptor != nil {
+		caller = func(ctx context.Context, req *PutRequest) (*PutResponse, error) {
+			resp, err := c.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*PutRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*PutRequest) when calling interceptor")
+					}
+					return c.callPutIfAbsent(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*PutResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*PutResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+	return caller(ctx, in)
+}
+
+func (c *goCaskJSONClient) callPutIfAbsent(ctx context.Context, in *PutRequest) (*PutResponse, error) {
+	out := new(PutResponse)
+	ctx, err := doJSONRequest(ctx, c.client, c.opts.Hooks, c.urls[5], in, out)
+	if err != nil {
+		twerr, ok := err.(twirp.Error)
+		if !ok {
+			twerr = twirp.InternalErrorWith(err)
+		}
+		callClientError(ctx, c.opts.Hooks, twerr)
+		return nil, err
+	}
+
+	callClientResponseReceived(ctx, c.opts.Hooks)
+
+	return out, nil
+}
+
+func (c *goCaskJSONClient) PutIfVersion(ctx context.Context, in *PutIfVersionRequest) (*PutResponse, error) {
+	ctx = ctxsetters.WithPackageName(ctx, "github.com.aneshas.gocask")
+	ctx = ctxsetters.WithServiceName(ctx, "GoCask")
+	ctx = ctxsetters.WithMethodName(ctx, "PutIfVersion")
+	caller := c.callPutIfVersion
+	if c.interceptor != nil {
+		caller = func(ctx context.Context, req *PutIfVersionRequest) (*PutResponse, error) {
+			resp, err := c.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*PutIfVersionRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*PutIfVersionRequest) when calling interceptor")
+					}
+					return c.callPutIfVersion(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*PutResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*PutResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+	return caller(ctx, in)
+}
+
+func (c *goCaskJSONClient) callPutIfVersion(ctx context.Context, in *PutIfVersionRequest) (*PutResponse, error) {
+	out := new(PutResponse)
+	ctx, err := doJSONRequest(ctx, c.client, c.opts.Hooks, c.urls[6], in, out)
+	if err != nil {
+		twerr, ok := err.(twirp.Error)
+		if !ok {
+			twerr = twirp.InternalErrorWith(err)
+		}
+		callClientError(ctx, c.opts.Hooks, twerr)
+		return nil, err
+	}
+
+	callClientResponseReceived(ctx, c.opts.Hooks)
+
+	return out, nil
+}
+
 // =====================
 // GoCask Server Handler
 // =====================
@@ -617,6 +905,15 @@ func (s *goCaskServer) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
 	case "Keys":
 		s.serveKeys(ctx, resp, req)
 		return
+	case "CompareAndSwap":
+		s.serveCompareAndSwap(ctx, resp, req)
+		return
+	case "PutIfAbsent":
+		s.servePutIfAbsent(ctx, resp, req)
+		return
+	case "PutIfVersion":
+		s.servePutIfVersion(ctx, resp, req)
+		return
 	default:
 		msg := fmt.Sprintf("no handler for path %q", req.URL.Path)
 		s.writeError(ctx, resp, badRouteError(msg, req.Method, req.URL.Path))
@@ -1344,22 +1641,562 @@ func (s *goCaskServer) serveKeysProtobuf(ctx context.Context, resp http.Response
 	callResponseSent(ctx, s.hooks)
 }
 
-func (s *goCaskServer) ServiceDescriptor() ([]byte, int) {
-	return twirpFileDescriptor0, 0
+func (s *goCaskServer) serveCompareAndSwap(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	header := req.Header.Get("Content-Type")
+	i := strings.Index(header, ";")
+	if i == -1 {
+		i = len(header)
+	}
+	switch strings.TrimSpace(strings.ToLower(header[:i])) {
+	case "application/json":
+		s.serveCompareAndSwapJSON(ctx, resp, req)
+	case "application/protobuf":
+		s.serveCompareAndSwapProtobuf(ctx, resp, req)
+	default:
+		msg := fmt.Sprintf("unexpected Content-Type: %q", req.Header.Get("Content-Type"))
+		twerr := badRouteError(msg, req.Method, req.URL.Path)
+		s.writeError(ctx, resp, twerr)
+	}
 }
 
-func (s *goCaskServer) ProtocGenTwirpVersion() string {
-	return "v8.1.2"
-}
+func (s *goCaskServer) serveCompareAndSwapJSON(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "CompareAndSwap")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
 
-// PathPrefix returns the base service path, in the form: "/<prefix>/<package>.<Service>/"
-// that is everything in a Twirp route except for the <Method>. This can be used for routing,
-// for example to identify the requests that are targeted to this service in a mux.
-func (s *goCaskServer) PathPrefix() string {
-	return baseServicePath(s.pathPrefix, "github.com.aneshas.gocask", "GoCask")
-}
+	d := json.NewDecoder(req.Body)
+	rawReqBody := json.RawMessage{}
+	if err := d.Decode(&rawReqBody); err != nil {
+		s.handleRequestBodyError(ctx, resp, "the json request could not be decoded", err)
+		return
+	}
+	reqContent := new(CompareAndSwapRequest)
+	unmarshaler := protojson.UnmarshalOptions{DiscardUnknown: true}
+	if err = unmarshaler.Unmarshal(rawReqBody, reqContent); err != nil {
+		s.handleRequestBodyError(ctx, resp, "the json request could not be decoded", err)
+		return
+	}
 
-// =====
+	handler := s.GoCask.CompareAndSwap
+	if s.interceptor != nil {
+		handler = func(ctx context.Context, req *CompareAndSwapRequest) (*PutResponse, error) {
+			resp, err := s.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*CompareAndSwapRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*CompareAndSwapRequest) when calling interceptor")
+					}
+					return s.GoCask.CompareAndSwap(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*PutResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*PutResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+
+	// Call service method
+	var respContent *PutResponse
+	func() {
+		defer ensurePanicResponses(ctx, resp, s.hooks)
+		respContent, err = handler(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *PutResponse and nil error while calling CompareAndSwap. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	marshaler := &protojson.MarshalOptions{UseProtoNames: !s.jsonCamelCase, EmitUnpopulated: !s.jsonSkipDefaults}
+	respBytes, err := marshaler.Marshal(respContent)
+	if err != nil {
+		s.writeError(ctx, resp, wrapInternal(err, "failed to marshal json response"))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/json")
+	resp.Header().Set("Content-Length", strconv.Itoa(len(respBytes)))
+	resp.WriteHeader(http.StatusOK)
+
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		ctx = callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *goCaskServer) serveCompareAndSwapProtobuf(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "CompareAndSwap")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	buf, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		s.handleRequestBodyError(ctx, resp, "failed to read request body", err)
+		return
+	}
+	reqContent := new(CompareAndSwapRequest)
+	if err = proto.Unmarshal(buf, reqContent); err != nil {
+		s.writeError(ctx, resp, malformedRequestError("the protobuf request could not be decoded"))
+		return
+	}
+
+	handler := s.GoCask.CompareAndSwap
+	if s.interceptor != nil {
+		handler = func(ctx context.Context, req *CompareAndSwapRequest) (*PutResponse, error) {
+			resp, err := s.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*CompareAndSwapRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*CompareAndSwapRequest) when calling interceptor")
+					}
+					return s.GoCask.CompareAndSwap(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*PutResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*PutResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+
+	// Call service method
+	var respContent *PutResponse
+	func() {
+		defer ensurePanicResponses(ctx, resp, s.hooks)
+		respContent, err = handler(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *PutResponse and nil error while calling CompareAndSwap. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	respBytes, err := proto.Marshal(respContent)
+	if err != nil {
+		s.writeError(ctx, resp, wrapInternal(err, "failed to marshal proto response"))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/protobuf")
+	resp.Header().Set("Content-Length", strconv.Itoa(len(respBytes)))
+	resp.WriteHeader(http.StatusOK)
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		ctx = callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *goCaskServer) servePutIfAbsent(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	header := req.Header.Get("Content-Type")
+	i := strings.Index(header, ";")
+	if i == -1 {
+		i = len(header)
+	}
+	switch strings.TrimSpace(strings.ToLower(header[:i])) {
+	case "application/json":
+		s.servePutIfAbsentJSON(ctx, resp, req)
+	case "application/protobuf":
+		s.servePutIfAbsentProtobuf(ctx, resp, req)
+	default:
+		msg := fmt.Sprintf("unexpected Content-Type: %q", req.Header.Get("Content-Type"))
+		twerr := badRouteError(msg, req.Method, req.URL.Path)
+		s.writeError(ctx, resp, twerr)
+	}
+}
+
+func (s *goCaskServer) servePutIfAbsentJSON(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "PutIfAbsent")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	d := json.NewDecoder(req.Body)
+	rawReqBody := json.RawMessage{}
+	if err := d.Decode(&rawReqBody); err != nil {
+		s.handleRequestBodyError(ctx, resp, "the json request could not be decoded", err)
+		return
+	}
+	reqContent := new(PutRequest)
+	unmarshaler := protojson.UnmarshalOptions{DiscardUnknown: true}
+	if err = unmarshaler.Unmarshal(rawReqBody, reqContent); err != nil {
+		s.handleRequestBodyError(ctx, resp, "the json request could not be decoded", err)
+		return
+	}
+
+	handler := s.GoCask.PutIfAbsent
+	if s.interceptor != nil {
+		handler = func(ctx context.Context, req *PutRequest) (*PutResponse, error) {
+			resp, err := s.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*PutRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*PutRequest) when calling interceptor")
+					}
+					return s.GoCask.PutIfAbsent(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*PutResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*PutResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+
+	// Call service method
+	var respContent *PutResponse
+	func() {
+		defer ensurePanicResponses(ctx, resp, s.hooks)
+		respContent, err = handler(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *PutResponse and nil error while calling PutIfAbsent. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	marshaler := &protojson.MarshalOptions{UseProtoNames: !s.jsonCamelCase, EmitUnpopulated: !s.jsonSkipDefaults}
+	respBytes, err := marshaler.Marshal(respContent)
+	if err != nil {
+		s.writeError(ctx, resp, wrapInternal(err, "failed to marshal json response"))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/json")
+	resp.Header().Set("Content-Length", strconv.Itoa(len(respBytes)))
+	resp.WriteHeader(http.StatusOK)
+
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		ctx = callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *goCaskServer) servePutIfAbsentProtobuf(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "PutIfAbsent")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	buf, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		s.handleRequestBodyError(ctx, resp, "failed to read request body", err)
+		return
+	}
+	reqContent := new(PutRequest)
+	if err = proto.Unmarshal(buf, reqContent); err != nil {
+		s.writeError(ctx, resp, malformedRequestError("the protobuf request could not be decoded"))
+		return
+	}
+
+	handler := s.GoCask.PutIfAbsent
+	if s.interceptor != nil {
+		handler = func(ctx context.Context, req *PutRequest) (*PutResponse, error) {
+			resp, err := s.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*PutRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*PutRequest) when calling interceptor")
+					}
+					return s.GoCask.PutIfAbsent(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*PutResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*PutResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+
+	// Call service method
+	var respContent *PutResponse
+	func() {
+		defer ensurePanicResponses(ctx, resp, s.hooks)
+		respContent, err = handler(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *PutResponse and nil error while calling PutIfAbsent. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	respBytes, err := proto.Marshal(respContent)
+	if err != nil {
+		s.writeError(ctx, resp, wrapInternal(err, "failed to marshal proto response"))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/protobuf")
+	resp.Header().Set("Content-Length", strconv.Itoa(len(respBytes)))
+	resp.WriteHeader(http.StatusOK)
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		ctx = callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *goCaskServer) servePutIfVersion(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	header := req.Header.Get("Content-Type")
+	i := strings.Index(header, ";")
+	if i == -1 {
+		i = len(header)
+	}
+	switch strings.TrimSpace(strings.ToLower(header[:i])) {
+	case "application/json":
+		s.servePutIfVersionJSON(ctx, resp, req)
+	case "application/protobuf":
+		s.servePutIfVersionProtobuf(ctx, resp, req)
+	default:
+		msg := fmt.Sprintf("unexpected Content-Type: %q", req.Header.Get("Content-Type"))
+		twerr := badRouteError(msg, req.Method, req.URL.Path)
+		s.writeError(ctx, resp, twerr)
+	}
+}
+
+func (s *goCaskServer) servePutIfVersionJSON(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "PutIfVersion")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	d := json.NewDecoder(req.Body)
+	rawReqBody := json.RawMessage{}
+	if err := d.Decode(&rawReqBody); err != nil {
+		s.handleRequestBodyError(ctx, resp, "the json request could not be decoded", err)
+		return
+	}
+	reqContent := new(PutIfVersionRequest)
+	unmarshaler := protojson.UnmarshalOptions{DiscardUnknown: true}
+	if err = unmarshaler.Unmarshal(rawReqBody, reqContent); err != nil {
+		s.handleRequestBodyError(ctx, resp, "the json request could not be decoded", err)
+		return
+	}
+
+	handler := s.GoCask.PutIfVersion
+	if s.interceptor != nil {
+		handler = func(ctx context.Context, req *PutIfVersionRequest) (*PutResponse, error) {
+			resp, err := s.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*PutIfVersionRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*PutIfVersionRequest) when calling interceptor")
+					}
+					return s.GoCask.PutIfVersion(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*PutResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*PutResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+
+	// Call service method
+	var respContent *PutResponse
+	func() {
+		defer ensurePanicResponses(ctx, resp, s.hooks)
+		respContent, err = handler(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *PutResponse and nil error while calling PutIfVersion. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	marshaler := &protojson.MarshalOptions{UseProtoNames: !s.jsonCamelCase, EmitUnpopulated: !s.jsonSkipDefaults}
+	respBytes, err := marshaler.Marshal(respContent)
+	if err != nil {
+		s.writeError(ctx, resp, wrapInternal(err, "failed to marshal json response"))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/json")
+	resp.Header().Set("Content-Length", strconv.Itoa(len(respBytes)))
+	resp.WriteHeader(http.StatusOK)
+
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		ctx = callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *goCaskServer) servePutIfVersionProtobuf(ctx context.Context, resp http.ResponseWriter, req *http.Request) {
+	var err error
+	ctx = ctxsetters.WithMethodName(ctx, "PutIfVersion")
+	ctx, err = callRequestRouted(ctx, s.hooks)
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+
+	buf, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		s.handleRequestBodyError(ctx, resp, "failed to read request body", err)
+		return
+	}
+	reqContent := new(PutIfVersionRequest)
+	if err = proto.Unmarshal(buf, reqContent); err != nil {
+		s.writeError(ctx, resp, malformedRequestError("the protobuf request could not be decoded"))
+		return
+	}
+
+	handler := s.GoCask.PutIfVersion
+	if s.interceptor != nil {
+		handler = func(ctx context.Context, req *PutIfVersionRequest) (*PutResponse, error) {
+			resp, err := s.interceptor(
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					typedReq, ok := req.(*PutIfVersionRequest)
+					if !ok {
+						return nil, twirp.InternalError("failed type assertion req.(*PutIfVersionRequest) when calling interceptor")
+					}
+					return s.GoCask.PutIfVersion(ctx, typedReq)
+				},
+			)(ctx, req)
+			if resp != nil {
+				typedResp, ok := resp.(*PutResponse)
+				if !ok {
+					return nil, twirp.InternalError("failed type assertion resp.(*PutResponse) when calling interceptor")
+				}
+				return typedResp, err
+			}
+			return nil, err
+		}
+	}
+
+	// Call service method
+	var respContent *PutResponse
+	func() {
+		defer ensurePanicResponses(ctx, resp, s.hooks)
+		respContent, err = handler(ctx, reqContent)
+	}()
+
+	if err != nil {
+		s.writeError(ctx, resp, err)
+		return
+	}
+	if respContent == nil {
+		s.writeError(ctx, resp, twirp.InternalError("received a nil *PutResponse and nil error while calling PutIfVersion. nil responses are not supported"))
+		return
+	}
+
+	ctx = callResponsePrepared(ctx, s.hooks)
+
+	respBytes, err := proto.Marshal(respContent)
+	if err != nil {
+		s.writeError(ctx, resp, wrapInternal(err, "failed to marshal proto response"))
+		return
+	}
+
+	ctx = ctxsetters.WithStatusCode(ctx, http.StatusOK)
+	resp.Header().Set("Content-Type", "application/protobuf")
+	resp.Header().Set("Content-Length", strconv.Itoa(len(respBytes)))
+	resp.WriteHeader(http.StatusOK)
+	if n, err := resp.Write(respBytes); err != nil {
+		msg := fmt.Sprintf("failed to write response, %d of %d bytes written: %s", n, len(respBytes), err.Error())
+		twerr := twirp.NewError(twirp.Unknown, msg)
+		ctx = callError(ctx, s.hooks, twerr)
+	}
+	callResponseSent(ctx, s.hooks)
+}
+
+func (s *goCaskServer) ServiceDescriptor() ([]byte, int) {
+	return twirpFileDescriptor0, 0
+}
+
+func (s *goCaskServer) ProtocGenTwirpVersion() string {
+	return "v8.1.2"
+}
+
+// PathPrefix returns the base service path, in the form: "/<prefix>/<package>.<Service>/"
+// that is everything in a Twirp route except for the <Method>. This can be used for routing,
+// for example to identify the requests that are targeted to this service in a mux.
+func (s *goCaskServer) PathPrefix() string {
+	return baseServicePath(s.pathPrefix, "github.com.aneshas.gocask", "GoCask")
+}
+
+// =====
 // Utils
 // =====
 
@@ -1482,9 +2319,12 @@ func sanitizeBaseURL(baseURL string) string {
 
 // baseServicePath composes the path prefix for the service (without <Method>).
 // e.g.: baseServicePath("/twirp", "my.pkg", "MyService")
-//       returns => "/twirp/my.pkg.MyService/"
+//
+//	returns => "/twirp/my.pkg.MyService/"
+//
 // e.g.: baseServicePath("", "", "MyService")
-//       returns => "/MyService/"
+//
+//	returns => "/MyService/"
 func baseServicePath(prefix, pkg, service string) string {
 	fullServiceName := service
 	if pkg != "" {
@@ -1922,23 +2762,30 @@ func callClientError(ctx context.Context, h *twirp.ClientHooks, err twirp.Error)
 }
 
 var twirpFileDescriptor0 = []byte{
-	// 274 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x92, 0x41, 0x4b, 0xc3, 0x30,
-	0x14, 0xc7, 0xe9, 0x6a, 0x2b, 0x3e, 0x26, 0x8c, 0xe0, 0xa1, 0x0e, 0xd4, 0x5a, 0x14, 0x7b, 0x4a,
-	0x41, 0xfd, 0x04, 0xea, 0xe8, 0x41, 0x90, 0x59, 0x3c, 0x79, 0xeb, 0xca, 0x63, 0x93, 0xae, 0x4d,
-	0xec, 0x4b, 0x84, 0x7c, 0x4c, 0xbf, 0x91, 0xb4, 0x1d, 0x54, 0x0f, 0x8d, 0xbb, 0xe5, 0x85, 0x5f,
-	0x7e, 0x79, 0xf9, 0xe7, 0xc1, 0xac, 0x91, 0x45, 0xb2, 0x16, 0x45, 0x4e, 0x25, 0x97, 0x8d, 0x50,
-	0x82, 0x9d, 0xae, 0x3f, 0xd4, 0x46, 0xaf, 0x78, 0x21, 0x2a, 0x9e, 0xd7, 0x48, 0x9b, 0x9c, 0x78,
-	0x0f, 0x44, 0x57, 0x30, 0x7d, 0x46, 0x43, 0x19, 0x92, 0x14, 0x35, 0x21, 0x3b, 0x01, 0xaf, 0x2a,
-	0xd1, 0x50, 0xe0, 0x84, 0x6e, 0x7c, 0x94, 0xf5, 0x45, 0x74, 0x0e, 0x90, 0xa2, 0xca, 0xf0, 0x53,
-	0x23, 0x29, 0x36, 0x03, 0xb7, 0x44, 0x13, 0x38, 0xa1, 0x13, 0x4f, 0xb3, 0x76, 0x19, 0x5d, 0xc2,
-	0xf1, 0x13, 0x6e, 0x51, 0xe1, 0x38, 0x72, 0x0f, 0xb0, 0xd4, 0xe3, 0x8a, 0xf6, 0xe2, 0xaf, 0x7c,
-	0xab, 0x31, 0x98, 0x74, 0x7b, 0x7d, 0x11, 0x25, 0xe0, 0x2d, 0x6a, 0xd5, 0x98, 0xbd, 0x0f, 0x1c,
-	0x82, 0xb7, 0xa8, 0xa4, 0x32, 0xb7, 0xdf, 0x13, 0xf0, 0x53, 0xf1, 0x98, 0x53, 0xc9, 0x5e, 0xc0,
-	0x5d, 0x6a, 0xc5, 0xae, 0xf9, 0x68, 0x0c, 0x7c, 0x68, 0x6d, 0x1e, 0x5a, 0xb0, 0x4e, 0xdd, 0xfa,
-	0x52, 0xb4, 0xfb, 0x86, 0xb4, 0xec, 0xbe, 0xee, 0x6d, 0x6f, 0xe0, 0xf7, 0xe9, 0xb1, 0xd8, 0xc2,
-	0xfe, 0x09, 0x78, 0x8f, 0x2e, 0x5f, 0xe1, 0xa0, 0xfd, 0x59, 0xf6, 0x2f, 0x39, 0xbf, 0xb1, 0x10,
-	0xbf, 0x87, 0xe3, 0xe1, 0xe2, 0xfd, 0x6c, 0x20, 0x93, 0x1d, 0xb9, 0x1b, 0xb5, 0xa4, 0x91, 0xc5,
-	0xca, 0xef, 0xe6, 0xed, 0xee, 0x27, 0x00, 0x00, 0xff, 0xff, 0xb7, 0xb7, 0x6a, 0x68, 0x83, 0x02,
-	0x00, 0x00,
+	// 398 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x94, 0x5d, 0x6f, 0xda, 0x30,
+	0x14, 0x86, 0x95, 0x85, 0xc0, 0x76, 0xc6, 0x26, 0xe4, 0x6d, 0x52, 0x16, 0x69, 0x1b, 0x8b, 0xb6,
+	0xc1, 0x55, 0x98, 0xb6, 0xfd, 0x01, 0x46, 0x51, 0x84, 0x2a, 0x55, 0x34, 0xad, 0x5a, 0xa9, 0xed,
+	0x4d, 0x08, 0x87, 0x8f, 0x86, 0xc4, 0x6e, 0xec, 0xd0, 0xe6, 0xba, 0x7f, 0xbc, 0xca, 0x47, 0x0b,
+	0xa9, 0x9a, 0x90, 0x4a, 0xbd, 0xe3, 0xa0, 0xc7, 0xcf, 0xb1, 0x73, 0x5e, 0x1b, 0x5a, 0x01, 0x73,
+	0x7a, 0x73, 0xea, 0xd8, 0xdc, 0x35, 0x58, 0x40, 0x05, 0x25, 0x9f, 0xe7, 0x4b, 0xb1, 0x08, 0x27,
+	0x86, 0x43, 0x3d, 0xc3, 0xf6, 0x91, 0x2f, 0x6c, 0x6e, 0xa4, 0x80, 0xfe, 0x03, 0x9a, 0xfb, 0x18,
+	0x71, 0x0b, 0x39, 0xa3, 0x3e, 0x47, 0xf2, 0x11, 0x14, 0xcf, 0xc5, 0x88, 0xab, 0x52, 0x5b, 0xee,
+	0xbe, 0xb1, 0xd2, 0x42, 0xff, 0x0a, 0x60, 0xa2, 0xb0, 0xf0, 0x2a, 0x44, 0x2e, 0x48, 0x0b, 0x64,
+	0x17, 0x23, 0x55, 0x6a, 0x4b, 0xdd, 0xa6, 0x15, 0xff, 0xd4, 0xbf, 0xc3, 0xbb, 0x3d, 0x5c, 0xa1,
+	0xc0, 0x62, 0xe4, 0x1f, 0xc0, 0x38, 0x2c, 0x56, 0xc4, 0x8d, 0xd7, 0xf6, 0x2a, 0x44, 0xf5, 0x55,
+	0xf2, 0x5f, 0x5a, 0xe8, 0xe7, 0xf0, 0x69, 0x40, 0x3d, 0x66, 0x07, 0xd8, 0xf7, 0xa7, 0x47, 0xd7,
+	0x36, 0x2b, 0x16, 0x68, 0xf0, 0x1a, 0x6f, 0x18, 0x3a, 0x02, 0xa7, 0x99, 0xe3, 0xa1, 0xde, 0xc8,
+	0xe5, 0x6d, 0xf9, 0x29, 0x7c, 0x18, 0x87, 0x62, 0x34, 0x3b, 0xc1, 0x80, 0x2f, 0xa9, 0xff, 0xcc,
+	0xbd, 0x11, 0x15, 0x1a, 0xeb, 0x74, 0x65, 0xa2, 0xad, 0x59, 0xf7, 0xa5, 0xde, 0x81, 0xb7, 0xc9,
+	0x59, 0xb3, 0x6f, 0xba, 0x05, 0x4a, 0x79, 0x70, 0x04, 0xca, 0xd0, 0x17, 0x41, 0xf4, 0x02, 0x3d,
+	0x1b, 0xa0, 0x0c, 0x3d, 0x26, 0xa2, 0x3f, 0xb7, 0x0a, 0xd4, 0x4d, 0x3a, 0xb0, 0xb9, 0x4b, 0x0e,
+	0x40, 0x1e, 0x87, 0x82, 0xfc, 0x34, 0x0a, 0xe7, 0x6f, 0x6c, 0x66, 0xa2, 0xb5, 0x4b, 0xb0, 0x44,
+	0x1d, 0xfb, 0x4c, 0x2c, 0xf7, 0x99, 0x58, 0xcd, 0x97, 0x9c, 0xfa, 0x18, 0xea, 0x69, 0x6c, 0x48,
+	0xb7, 0x84, 0xcd, 0x25, 0xab, 0xc2, 0x2e, 0x0f, 0xa1, 0x16, 0x47, 0x9a, 0xec, 0x24, 0xb5, 0x4e,
+	0x09, 0x91, 0xbb, 0x15, 0x97, 0xf0, 0x3e, 0x1f, 0x43, 0xf2, 0xbb, 0x64, 0xe9, 0x93, 0x89, 0xd5,
+	0x7e, 0xed, 0x9a, 0x42, 0xd6, 0xeb, 0x22, 0x09, 0xcf, 0x68, 0xd6, 0x9f, 0x70, 0xf4, 0x2b, 0x0f,
+	0xaf, 0xaa, 0x7d, 0x06, 0xcd, 0xed, 0xcc, 0x13, 0xa3, 0x7c, 0xdd, 0xe3, 0xcb, 0x51, 0xb5, 0xcf,
+	0xff, 0x6f, 0x67, 0x5f, 0x36, 0x60, 0x2f, 0x03, 0xb3, 0x57, 0xa9, 0x17, 0x30, 0x67, 0x52, 0x4f,
+	0x9e, 0xa6, 0xbf, 0x77, 0x03, 0x00, 0x5d, 0x2e, 0x00, 0x0f, 0xae, 0x04, 0x00, 0x00,
 }